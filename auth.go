@@ -0,0 +1,122 @@
+package techo
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+// basicAuth rejects requests that don't carry the expected basic auth
+// credentials.
+type basicAuth struct {
+	mutex sync.Mutex
+	user  string
+	pass  string
+}
+
+func (a *basicAuth) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		a.mutex.Lock()
+		wantUser, wantPass := a.user, a.pass
+		a.mutex.Unlock()
+
+		user, pass, ok := stdRequest(c).BasicAuth()
+		if !ok || user != wantUser || pass != wantPass {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="techo"`)
+			return echo.ErrUnauthorized
+		}
+		return next(c)
+	}
+}
+
+// RequireBasicAuth installs middleware that responds with 401 Unauthorized (and
+// a WWW-Authenticate header) to any request that doesn't carry basic auth
+// credentials matching user and pass. Calling RequireBasicAuth again replaces
+// the expected credentials.
+func (t *Techo) RequireBasicAuth(user, pass string) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.basicAuth == nil {
+		t.basicAuth = &basicAuth{}
+		t.Use(t.basicAuth.middleware)
+	}
+
+	t.basicAuth.mutex.Lock()
+	defer t.basicAuth.mutex.Unlock()
+	t.basicAuth.user = user
+	t.basicAuth.pass = pass
+}
+
+// bearerAuth rejects requests that don't carry the expected bearer token, and
+// captures the tokens that were presented.
+type bearerAuth struct {
+	mutex    sync.Mutex
+	token    string
+	captured []string
+}
+
+func (a *bearerAuth) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		const prefix = "Bearer "
+		auth := c.Request().Header().Get(echo.HeaderAuthorization)
+		ok := strings.HasPrefix(auth, prefix)
+		token := strings.TrimPrefix(auth, prefix)
+
+		a.mutex.Lock()
+		if ok {
+			a.captured = append(a.captured, token)
+		}
+		want := a.token
+		a.mutex.Unlock()
+
+		if !ok || token == "" || (want != "" && token != want) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="techo"`)
+			return echo.ErrUnauthorized
+		}
+		return next(c)
+	}
+}
+
+// RequireBearer installs middleware that responds with 401 Unauthorized to any
+// request that doesn't carry the header "Authorization: Bearer <token>". If
+// token is empty, any (non-empty) bearer token is accepted: this is useful when
+// the test doesn't know the token in advance, but wants to inspect it via
+// BearerTokens. Calling RequireBearer again replaces the expected token.
+func (t *Techo) RequireBearer(token string) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.bearerAuth == nil {
+		t.bearerAuth = &bearerAuth{}
+		t.Use(t.bearerAuth.middleware)
+	}
+
+	t.bearerAuth.mutex.Lock()
+	defer t.bearerAuth.mutex.Unlock()
+	t.bearerAuth.token = token
+}
+
+// BearerTokens returns the bearer tokens presented by requests (whether valid
+// or not) since RequireBearer was invoked, in the order they were received.
+func (t *Techo) BearerTokens() []string {
+
+	t.mutex.Lock()
+	a := t.bearerAuth
+	t.mutex.Unlock()
+
+	if a == nil {
+		return nil
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	tokens := make([]string, len(a.captured))
+	copy(tokens, a.captured)
+	return tokens
+}
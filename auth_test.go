@@ -0,0 +1,73 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireBasicAuth(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/secret", http.StatusOK, "secret")
+	te.RequireBasicAuth("alice", "s3cret")
+
+	// No credentials
+	resp, err := http.Get(te.AbsURL("/secret"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get(echo.HeaderWWWAuthenticate))
+
+	// Wrong credentials
+	req, err := http.NewRequest(echo.GET, te.AbsURL("/secret"), nil)
+	require.Nil(t, err)
+	req.SetBasicAuth("alice", "wrong")
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Correct credentials
+	req.SetBasicAuth("alice", "s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRequireBearer(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/secret", http.StatusOK, "secret")
+	te.RequireBearer("t0ken")
+
+	get := func(auth string) int {
+		req, err := http.NewRequest(echo.GET, te.AbsURL("/secret"), nil)
+		require.Nil(t, err)
+		if auth != "" {
+			req.Header.Set(echo.HeaderAuthorization, auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get(""))
+	assert.Equal(t, http.StatusUnauthorized, get("Bearer wrong"))
+	assert.Equal(t, http.StatusOK, get("Bearer t0ken"))
+	assert.Equal(t, []string{"wrong", "t0ken"}, te.BearerTokens())
+
+	// Accept any token, and inspect what was sent
+	te.RequireBearer("")
+	assert.Equal(t, http.StatusUnauthorized, get(""))
+	assert.Equal(t, http.StatusOK, get("Bearer whatever"))
+	tokens := te.BearerTokens()
+	assert.Equal(t, "whatever", tokens[len(tokens)-1])
+}
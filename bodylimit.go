@@ -0,0 +1,52 @@
+package techo
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// bodyLimit rejects requests whose body exceeds a limit, using echo's body
+// limit middleware. The limit can be changed (or removed) after installation.
+type bodyLimit struct {
+	mutex sync.Mutex
+	mw    echo.MiddlewareFunc // nil if there is no limit
+}
+
+func (b *bodyLimit) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		b.mutex.Lock()
+		mw := b.mw
+		b.mutex.Unlock()
+
+		if mw == nil {
+			return next(c)
+		}
+		return mw(next)(c)
+	}
+}
+
+// SetMaxBodySize causes requests with a body larger than the supplied number of
+// bytes to be rejected with 413 Request Entity Too Large. A size of zero or less
+// removes the limit.
+func (t *Techo) SetMaxBodySize(bytes int64) {
+
+	t.mutex.Lock()
+	if t.bodyLimit == nil {
+		t.bodyLimit = &bodyLimit{}
+		t.Use(t.bodyLimit.middleware)
+	}
+	b := t.bodyLimit
+	t.mutex.Unlock()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if bytes <= 0 {
+		b.mw = nil
+		return
+	}
+	b.mw = middleware.BodyLimit(fmt.Sprintf("%vB", bytes))
+}
@@ -0,0 +1,40 @@
+package techo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetMaxBodySize(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.POST("/upload", func(c echo.Context) error {
+		body, err := ioutil.ReadAll(c.Request().Body())
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	})
+	te.SetMaxBodySize(10)
+
+	status, body, err := te.Post("/upload", "text/plain", strings.NewReader("small"))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "small", string(body))
+
+	status, _, err = te.Post("/upload", "text/plain", strings.NewReader("this body is too large"))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	te.SetMaxBodySize(0)
+	status, _, err = te.Post("/upload", "text/plain", strings.NewReader("this body is too large"))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,78 @@
+package techo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// client returns the http.Client used by request helpers such as Get. The
+// client is created (via Client) on first use.
+func (t *Techo) client() *http.Client {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.httpClient == nil {
+		t.httpClient = t.Client()
+	}
+	return t.httpClient
+}
+
+// Get performs a GET request for path (which is resolved via AbsURL), and
+// returns the response status code and body. For example:
+//
+//	status, body, err := te.Get("/hello")
+func (t *Techo) Get(path string) (status int, body []byte, err error) {
+
+	resp, err := t.client().Get(t.AbsURL(path))
+	if err != nil {
+		return 0, nil, err
+	}
+	return readResponse(resp)
+}
+
+// GetJSON performs a GET request for path (which is resolved via AbsURL), and
+// unmarshals the response body into v, returning the response status code. The
+// body is decoded whatever the status code, e.g. to capture a JSON error
+// response, so callers should check the status. If the body cannot be decoded,
+// an error is returned, along with the status code.
+func (t *Techo) GetJSON(path string, v interface{}) (status int, err error) {
+
+	status, body, err := t.Get(path)
+	if err != nil {
+		return status, err
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return status, fmt.Errorf("techo: unable to decode JSON response (status %v) from %v: %v", status, path, err)
+	}
+	return status, nil
+}
+
+// Post performs a POST request for path (which is resolved via AbsURL), with the
+// supplied content type and body, and returns the response status code and body.
+// The body may be nil.
+func (t *Techo) Post(path, contentType string, body io.Reader) (status int, respBody []byte, err error) {
+
+	resp, err := t.client().Post(t.AbsURL(path), contentType, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return readResponse(resp)
+}
+
+// readResponse reads and closes the body of resp, returning the status code
+// and body.
+func readResponse(resp *http.Response) (status int, body []byte, err error) {
+
+	defer resp.Body.Close()
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
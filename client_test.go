@@ -0,0 +1,105 @@
+package techo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+
+	te := NewTLS()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	status, body, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	status, _, err = te.Get("/nope")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestGetJSON(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type apiError struct {
+		Error string `json:"error"`
+	}
+
+	require.Nil(t, te.RespondJSON(echo.GET, "/users/1", http.StatusOK, user{ID: 1, Name: "alice"}))
+	require.Nil(t, te.RespondJSON(echo.GET, "/users/2", http.StatusNotFound, apiError{Error: "no such user"}))
+	te.Respond(echo.GET, "/text", http.StatusOK, "not json")
+
+	var u user
+	status, err := te.GetJSON("/users/1", &u)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, user{ID: 1, Name: "alice"}, u)
+
+	// A non-2xx response is still decoded.
+	var e apiError
+	status, err = te.GetJSON("/users/2", &e)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "no such user", e.Error)
+
+	status, err = te.GetJSON("/text", &u)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestPost(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.POST("/json", func(c echo.Context) error {
+		m := map[string]string{}
+		err := c.Bind(&m)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, c.Request().Header().Get(echo.HeaderContentType)+" "+m["name"])
+	})
+	te.POST("/form", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.FormValue("name"))
+	})
+	te.POST("/empty", func(c echo.Context) error {
+		body, err := ioutil.ReadAll(c.Request().Body())
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	})
+
+	status, body, err := te.Post("/json", echo.MIMEApplicationJSON, bytes.NewBufferString(`{"name":"techo"}`))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "application/json techo", string(body))
+
+	form := url.Values{"name": []string{"techo & co"}}
+	status, body, err = te.Post("/form", echo.MIMEApplicationForm, strings.NewReader(form.Encode()))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "techo & co", string(body))
+
+	status, body, err = te.Post("/empty", echo.MIMETextPlain, nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, body)
+}
@@ -0,0 +1,64 @@
+package techo
+
+import (
+	"sync"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// CORSConfig is the configuration for EnableCORS.
+type CORSConfig struct {
+	// AllowOrigins is the origins that may access the server. If empty, any
+	// origin ("*") is allowed.
+	AllowOrigins []string
+	// AllowMethods is the methods allowed in response to a preflight request.
+	// If empty, echo's default methods are allowed.
+	AllowMethods []string
+	// AllowHeaders is the request headers allowed in response to a preflight
+	// request.
+	AllowHeaders []string
+}
+
+// cors applies echo's CORS middleware. The config can be changed after
+// installation.
+type cors struct {
+	mutex sync.Mutex
+	mw    echo.MiddlewareFunc
+}
+
+func (cs *cors) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		cs.mutex.Lock()
+		mw := cs.mw
+		cs.mutex.Unlock()
+
+		return mw(next)(c)
+	}
+}
+
+// EnableCORS installs echo's CORS middleware with the supplied config, so that
+// the server responds to CORS preflight (OPTIONS) requests, and adds CORS
+// headers to responses. Calling EnableCORS again replaces the config.
+func (t *Techo) EnableCORS(cfg CORSConfig) {
+
+	mw := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.AllowOrigins,
+		AllowMethods: cfg.AllowMethods,
+		AllowHeaders: cfg.AllowHeaders,
+	})
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cors == nil {
+		t.cors = &cors{mw: mw}
+		t.Use(t.cors.middleware)
+		return
+	}
+
+	t.cors.mutex.Lock()
+	t.cors.mw = mw
+	t.cors.mutex.Unlock()
+}
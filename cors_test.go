@@ -0,0 +1,48 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableCORS(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.POST, "/things", http.StatusOK, "ok")
+	te.EnableCORS(CORSConfig{
+		AllowOrigins: []string{"https://example.com"},
+		AllowMethods: []string{echo.GET, echo.POST},
+		AllowHeaders: []string{"X-Custom"},
+	})
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, te.AbsURL("/things"), nil)
+		require.Nil(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := preflight("https://example.com")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), http.MethodPost)
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "X-Custom")
+
+	resp = preflight("https://evil.example.com")
+	assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// Replacing the config takes effect.
+	te.EnableCORS(CORSConfig{AllowOrigins: []string{"*"}})
+	resp = preflight("https://evil.example.com")
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+}
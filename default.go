@@ -0,0 +1,51 @@
+package techo
+
+import "sync"
+
+// def holds the default instance returned by Default.
+var def = struct {
+	sync.Mutex
+	once *sync.Once
+	te   *Techo
+}{once: &sync.Once{}}
+
+// Default returns a shared techo instance, starting it (via New) on first use.
+// Subsequent calls return the same instance, until it is released via
+// StopDefault. This is convenient for examples and quick experiments; tests
+// should generally use their own instance. In the unlikely event that the
+// server cannot be started, the error is logged and nil is returned; the next
+// call to Default tries again.
+func Default() *Techo {
+	return defaultWith(New)
+}
+
+// defaultWith implements Default, using start to start the instance.
+func defaultWith(start func() *Techo) *Techo {
+
+	def.Lock()
+	defer def.Unlock()
+
+	def.once.Do(func() {
+		def.te = start()
+	})
+	if def.te == nil {
+		// Don't treat a failed start as done, so that it is retried.
+		def.once = &sync.Once{}
+	}
+	return def.te
+}
+
+// StopDefault stops and releases the instance returned by Default, if any. A
+// later call to Default starts a new instance.
+func StopDefault() {
+
+	def.Lock()
+	te := def.te
+	def.te = nil
+	def.once = &sync.Once{}
+	def.Unlock()
+
+	if te != nil {
+		te.Stop()
+	}
+}
@@ -0,0 +1,61 @@
+package techo
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault(t *testing.T) {
+
+	defer StopDefault()
+
+	te := Default()
+	require.NotNil(t, te)
+	assert.True(t, te == Default(), "should be the same instance")
+
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	status, body, err := Default().Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	StopDefault()
+	StopDefault() // No-op
+
+	// The released instance is stopped, so its port is free.
+	l, err := net.Listen("tcp", te.Addr.String())
+	require.Nil(t, err)
+	l.Close()
+
+	te2 := Default()
+	require.NotNil(t, te2)
+	assert.False(t, te == te2, "should be a new instance")
+	status, _, err = te2.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestDefaultRetriesFailedStart(t *testing.T) {
+
+	defer StopDefault()
+	StopDefault()
+
+	var calls int
+	failing := func() *Techo {
+		calls++
+		return nil
+	}
+
+	assert.Nil(t, defaultWith(failing))
+	assert.Nil(t, defaultWith(failing))
+	assert.Equal(t, 2, calls, "a failed start should be retried")
+
+	te := Default()
+	require.NotNil(t, te)
+	assert.True(t, te == Default())
+}
@@ -0,0 +1,40 @@
+package techo
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/labstack/echo"
+)
+
+// drainBody is middleware that reads the remainder of the request body after
+// the handler returns.
+func drainBody(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		err := next(c)
+
+		body := stdRequest(c).Body
+		io.Copy(ioutil.Discard, body)
+		body.Close()
+		return err
+	}
+}
+
+// AlwaysDrainBody installs middleware that fully reads (and discards) the
+// request body after each handler returns, even if the handler did not read
+// the body. Otherwise, if a large body is left unread, the server closes the
+// connection instead of reusing it. It is safe to call AlwaysDrainBody multiple
+// times.
+func (t *Techo) AlwaysDrainBody() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.drainBody {
+		return
+	}
+
+	t.drainBody = true
+	t.Use(drainBody)
+}
@@ -0,0 +1,40 @@
+package techo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlwaysDrainBody(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.AlwaysDrainBody()
+	te.AlwaysDrainBody()
+
+	// The handler does not read the body.
+	te.Respond(echo.POST, "/upload", http.StatusOK, "ok")
+
+	// The body is larger than the amount the http server itself will discard
+	// before giving up on the connection.
+	payload := bytes.Repeat([]byte("x"), 1<<20)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Post(te.AbsURL("/upload"), "text/plain", bytes.NewReader(payload))
+		require.Nil(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// All requests were made over a single connection.
+	assert.Equal(t, 1, te.ConnectionsAccepted())
+}
@@ -0,0 +1,138 @@
+package techo
+
+import (
+	"math/rand"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// faults injects failures into a proportion of requests.
+type faults struct {
+	mutex  sync.Mutex
+	rate   float64
+	status int
+	rnd    *rand.Rand
+}
+
+func (f *faults) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		f.mutex.Lock()
+		fail := f.rate >= 1 || (f.rate > 0 && f.rnd.Float64() < f.rate)
+		status := f.status
+		f.mutex.Unlock()
+
+		if fail {
+			return echo.NewHTTPError(status)
+		}
+		return next(c)
+	}
+}
+
+// getFaults returns t's faults, installing the faults middleware if necessary.
+func (t *Techo) getFaults() *faults {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.faults == nil {
+		t.faults = &faults{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+		t.Use(t.faults.middleware)
+	}
+	return t.faults
+}
+
+// SetFailureRate causes approximately the supplied fraction of requests to fail
+// with the supplied status code, instead of being handled. A rate of 0 means no
+// requests fail; a rate of 1 means every request fails. Use SetFailureSeed
+// to make the sequence of failures deterministic.
+func (t *Techo) SetFailureRate(rate float64, status int) {
+
+	f := t.getFaults()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rate = rate
+	f.status = status
+}
+
+// SetFailureSeed seeds the random number generator used by SetFailureRate.
+func (t *Techo) SetFailureSeed(seed int64) {
+
+	f := t.getFaults()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rnd = rand.New(rand.NewSource(seed))
+}
+
+// PanicRecord describes a panic that occurred in a handler, as captured when
+// panic capture is enabled. See Techo.EnablePanicCapture.
+type PanicRecord struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// panics captures handler panics.
+type panics struct {
+	mutex   sync.Mutex
+	records []PanicRecord
+}
+
+func (p *panics) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			p.mutex.Lock()
+			p.records = append(p.records, PanicRecord{Value: v, Stack: debug.Stack()})
+			p.mutex.Unlock()
+			err = echo.NewHTTPError(http.StatusInternalServerError)
+		}()
+
+		return next(c)
+	}
+}
+
+// EnablePanicCapture installs middleware that recovers from panics in handlers.
+// The server responds with status 500, and the panic is recorded, available
+// via Panics. It is safe to call EnablePanicCapture multiple times.
+func (t *Techo) EnablePanicCapture() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.panics != nil {
+		return
+	}
+
+	t.panics = &panics{}
+	t.Use(t.panics.middleware)
+}
+
+// Panics returns a copy of the handler panics captured so far. It returns nil
+// if panic capture has not been enabled.
+func (t *Techo) Panics() []PanicRecord {
+
+	t.mutex.Lock()
+	p := t.panics
+	t.mutex.Unlock()
+
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	records := make([]PanicRecord, len(p.records))
+	copy(records, p.records)
+	return records
+}
@@ -0,0 +1,54 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetFailureRate(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	te.SetFailureSeed(42)
+	te.SetFailureRate(1.0, http.StatusInternalServerError)
+	for i := 0; i < 10; i++ {
+		resp, err := http.Get(te.AbsURL("/hello"))
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	te.SetFailureRate(0, http.StatusInternalServerError)
+	for i := 0; i < 10; i++ {
+		resp, err := http.Get(te.AbsURL("/hello"))
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestEnablePanicCapture(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnablePanicCapture()
+	te.GET("/panic", func(c echo.Context) error {
+		panic("oh no")
+	})
+
+	resp, err := http.Get(te.AbsURL("/panic"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	panics := te.Panics()
+	require.Equal(t, 1, len(panics))
+	assert.Equal(t, "oh no", panics[0].Value)
+	assert.NotEmpty(t, panics[0].Stack)
+}
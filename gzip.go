@@ -0,0 +1,73 @@
+package techo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// EnableGzip installs echo's gzip middleware, so that responses are gzipped
+// for clients that send "Accept-Encoding: gzip". Responses to other clients are
+// not compressed. It is safe to call EnableGzip multiple times.
+func (t *Techo) EnableGzip() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.gzipEnabled {
+		return
+	}
+
+	t.gzipEnabled = true
+	t.Use(middleware.Gzip())
+}
+
+// RespondGzipJSON is like RespondJSON, but the response is gzipped if the client
+// sends "Accept-Encoding: gzip". It does not require EnableGzip. The value is
+// marshaled and compressed once, at registration time. If EnableGzip is in
+// effect, the plain JSON is written, and compressed by the gzip middleware, so
+// that the response is not compressed twice.
+func (t *Techo) RespondGzipJSON(method, path string, status int, v interface{}) error {
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	_, err = gw.Write(b)
+	if err != nil {
+		return err
+	}
+	err = gw.Close()
+	if err != nil {
+		return err
+	}
+	gz := buf.Bytes()
+
+	t.Match([]string{method}, path, func(c echo.Context) error {
+
+		header := c.Response().Header()
+		if header.Get(echo.HeaderContentEncoding) == "gzip" {
+			// The gzip middleware (see EnableGzip) is compressing the response.
+			return c.JSONBlob(status, b)
+		}
+
+		header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+		if !strings.Contains(c.Request().Header().Get(echo.HeaderAcceptEncoding), "gzip") {
+			return c.JSONBlob(status, b)
+		}
+
+		header.Set(echo.HeaderContentEncoding, "gzip")
+		header.Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		c.Response().WriteHeader(status)
+		_, err := c.Response().Write(gz)
+		return err
+	})
+	return nil
+}
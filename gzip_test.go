@@ -0,0 +1,107 @@
+package techo
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getRaw makes a GET request with transparent decompression disabled, so that
+// the response is seen as sent by the server. If acceptGzip is true, the
+// request has header "Accept-Encoding: gzip".
+func getRaw(t *testing.T, url string, acceptGzip bool) *http.Response {
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.Nil(t, err)
+	if acceptGzip {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	require.Nil(t, err)
+	return resp
+}
+
+func TestEnableGzip(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnableGzip()
+	te.EnableGzip()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	resp := getRaw(t, te.AbsURL("/hello"), true)
+	defer resp.Body.Close()
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	gr, err := gzip.NewReader(resp.Body)
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(gr)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	resp2 := getRaw(t, te.AbsURL("/hello"), false)
+	defer resp2.Body.Close()
+	assert.Equal(t, "", resp2.Header.Get("Content-Encoding"))
+	body, err = ioutil.ReadAll(resp2.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestRespondGzipJSON(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	err := te.RespondGzipJSON(echo.GET, "/thing", http.StatusOK, map[string]string{"name": "widget"})
+	require.Nil(t, err)
+
+	resp := getRaw(t, te.AbsURL("/thing"), true)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+	gr, err := gzip.NewReader(resp.Body)
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(gr)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"name":"widget"}`, string(body))
+
+	resp2 := getRaw(t, te.AbsURL("/thing"), false)
+	defer resp2.Body.Close()
+	assert.Equal(t, "", resp2.Header.Get("Content-Encoding"))
+	body, err = ioutil.ReadAll(resp2.Body)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"name":"widget"}`, string(body))
+}
+
+func TestRespondGzipJSONWithEnableGzip(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnableGzip()
+	err := te.RespondGzipJSON(echo.GET, "/thing", http.StatusOK, map[string]string{"name": "widget"})
+	require.Nil(t, err)
+
+	// The body is compressed exactly once.
+	resp := getRaw(t, te.AbsURL("/thing"), true)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	gr, err := gzip.NewReader(resp.Body)
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(gr)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"name":"widget"}`, string(body))
+
+	resp2 := getRaw(t, te.AbsURL("/thing"), false)
+	defer resp2.Body.Close()
+	assert.Equal(t, "", resp2.Header.Get("Content-Encoding"))
+	body, err = ioutil.ReadAll(resp2.Body)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"name":"widget"}`, string(body))
+}
@@ -0,0 +1,64 @@
+package techo
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+// responseHeaders adds a set of headers to every response.
+type responseHeaders struct {
+	mutex  sync.Mutex
+	header http.Header
+}
+
+func (h *responseHeaders) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		header := c.Response().Header()
+		h.mutex.Lock()
+		for name, vals := range h.header {
+			for _, val := range vals {
+				header.Add(name, val)
+			}
+		}
+		h.mutex.Unlock()
+
+		return next(c)
+	}
+}
+
+// SetResponseHeader causes the named header to be set to value on every
+// response. Calling it again with the same name replaces the value. Use
+// ClearResponseHeaders to remove all such headers.
+func (t *Techo) SetResponseHeader(name, value string) {
+
+	t.mutex.Lock()
+	if t.responseHeaders == nil {
+		t.responseHeaders = &responseHeaders{header: http.Header{}}
+		t.Use(t.responseHeaders.middleware)
+	}
+	h := t.responseHeaders
+	t.mutex.Unlock()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.header.Set(name, value)
+}
+
+// ClearResponseHeaders removes all headers set via SetResponseHeader.
+func (t *Techo) ClearResponseHeaders() {
+
+	t.mutex.Lock()
+	h := t.responseHeaders
+	t.mutex.Unlock()
+
+	if h == nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.header = http.Header{}
+}
@@ -0,0 +1,37 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetResponseHeader(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/a", http.StatusOK, "a")
+	te.Respond(echo.GET, "/b", http.StatusOK, "b")
+
+	te.SetResponseHeader("X-Frame-Options", "DENY")
+	te.SetResponseHeader("X-Custom", "first")
+	te.SetResponseHeader("X-Custom", "second")
+
+	for _, path := range []string{"/a", "/b"} {
+		resp, err := http.Get(te.AbsURL(path))
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"), path)
+		assert.Equal(t, []string{"second"}, resp.Header["X-Custom"], path)
+	}
+
+	te.ClearResponseHeaders()
+	resp, err := http.Get(te.AbsURL("/a"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "", resp.Header.Get("X-Frame-Options"))
+	assert.Equal(t, "", resp.Header.Get("X-Custom"))
+}
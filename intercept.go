@@ -0,0 +1,111 @@
+package techo
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/engine/standard"
+)
+
+// ResponseInterceptor is a function that may rewrite the status code and body
+// of a response before it is sent. See Techo.SetResponseInterceptor.
+type ResponseInterceptor func(c echo.Context, status int, body []byte) (int, []byte)
+
+// interceptor buffers each response, and passes it through fn before sending.
+// Its middleware is installed with Pre, so that it runs outside any other
+// middleware: errors are rendered only after they have passed through all other
+// middleware (e.g. SetStrict or SetNotFoundStatus), whichever order the
+// features were set in.
+type interceptor struct {
+	mutex sync.Mutex
+	fn    ResponseInterceptor
+}
+
+func (i *interceptor) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		i.mutex.Lock()
+		fn := i.fn
+		i.mutex.Unlock()
+
+		if fn == nil {
+			return next(c)
+		}
+
+		res := c.Response().(*standard.Response)
+		w, writer := res.ResponseWriter, res.Writer()
+		buf := &bufferedResponseWriter{header: w.Header(), status: http.StatusOK}
+		res.ResponseWriter = buf
+		res.SetWriter(buf)
+
+		// Errors are handled here, so that error responses are also intercepted.
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+
+		res.ResponseWriter = w
+		res.SetWriter(writer)
+
+		status, body := fn(c, buf.status, buf.body.Bytes())
+		w.Header().Set(echo.HeaderContentLength, strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		writer.Write(body)
+		return nil
+	}
+}
+
+// bufferedResponseWriter is an http.ResponseWriter that captures the status
+// code and body, instead of sending them. The header is shared with the
+// underlying http.ResponseWriter.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// SetResponseInterceptor causes every response to be passed through fn before
+// it is sent, allowing the status code and body to be rewritten. This is useful
+// for injecting faults into otherwise normal responses. For example, to turn
+// each 200 into a 500:
+//
+//	te.SetResponseInterceptor(func(c echo.Context, status int, body []byte) (int, []byte) {
+//		if status == http.StatusOK {
+//			return http.StatusInternalServerError, []byte("injected fault")
+//		}
+//		return status, body
+//	})
+//
+// Responses are buffered in their entirety, so streaming responses are not
+// streamed while an interceptor is set. The interceptor runs outside all other
+// middleware, so fn sees the response as it would otherwise be sent (e.g.
+// compressed, if EnableGzip is set). A nil fn removes the interceptor.
+func (t *Techo) SetResponseInterceptor(fn ResponseInterceptor) {
+
+	t.mutex.Lock()
+	if t.interceptor == nil {
+		t.interceptor = &interceptor{}
+		t.Pre(t.interceptor.middleware)
+	}
+	i := t.interceptor
+	t.mutex.Unlock()
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.fn = fn
+}
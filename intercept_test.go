@@ -0,0 +1,103 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetResponseInterceptor(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/ok", http.StatusOK, "all good")
+	te.Respond(echo.GET, "/created", http.StatusCreated, "created")
+
+	var paths []string
+	te.SetResponseInterceptor(func(c echo.Context, status int, body []byte) (int, []byte) {
+		paths = append(paths, c.Request().URL().Path())
+		if status == http.StatusOK {
+			return http.StatusInternalServerError, []byte("injected fault")
+		}
+		return status, body
+	})
+
+	status, body, err := te.Get("/ok")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "injected fault", string(body))
+
+	status, body, err = te.Get("/created")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created", string(body))
+
+	// Error responses are intercepted too.
+	status, _, err = te.Get("/missing")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, []string{"/ok", "/created", "/missing"}, paths)
+
+	te.SetResponseInterceptor(nil)
+	status, body, err = te.Get("/ok")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "all good", string(body))
+}
+
+func TestSetResponseInterceptorWithOtherFeatures(t *testing.T) {
+
+	testCases := []struct {
+		name             string
+		interceptorFirst bool
+	}{
+		{"interceptor_first", true},
+		{"interceptor_last", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			te := New()
+			defer te.Stop()
+			te.Respond(echo.GET, "/ok", http.StatusOK, "all good")
+
+			var statuses []int
+			intercept := func() {
+				te.SetResponseInterceptor(func(c echo.Context, status int, body []byte) (int, []byte) {
+					statuses = append(statuses, status)
+					return status, body
+				})
+			}
+
+			tb := &fakeTB{TB: t}
+			if tc.interceptorFirst {
+				intercept()
+			}
+			te.SetStrict(tb)
+			te.SetNotFoundStatus(http.StatusServiceUnavailable)
+			if !tc.interceptorFirst {
+				intercept()
+			}
+
+			status, _, err := te.Get("/ok")
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Empty(t, tb.Errors())
+
+			// The interceptor sees the error response as converted by
+			// SetNotFoundStatus, and strict mode still reports the request.
+			status, _, err = te.Get("/missing")
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, status)
+			assert.Equal(t, []int{http.StatusOK, http.StatusServiceUnavailable}, statuses)
+
+			errs := tb.Errors()
+			require.Equal(t, 1, len(errs))
+			assert.Contains(t, errs[0], "GET /missing")
+		})
+	}
+}
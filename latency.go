@@ -0,0 +1,165 @@
+package techo
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// latency is the delay applied to each request before it is handled. The delay
+// is stored atomically so that it can be adjusted while the server is running.
+type latency struct {
+	d int64
+}
+
+func (l *latency) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		d := time.Duration(atomic.LoadInt64(&l.d))
+		if d > 0 {
+			time.Sleep(d)
+		}
+		return next(c)
+	}
+}
+
+// SetLatency causes the server to wait for d before handling each request. This
+// is useful for testing client timeout and retry logic. The latency can be
+// adjusted at any time, even while requests are in flight.
+func (t *Techo) SetLatency(d time.Duration) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.latency == nil {
+		t.latency = &latency{}
+		t.Use(t.latency.middleware)
+	}
+	atomic.StoreInt64(&t.latency.d, int64(d))
+}
+
+// ClearLatency removes any latency set via SetLatency.
+func (t *Techo) ClearLatency() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.latency != nil {
+		atomic.StoreInt64(&t.latency.d, 0)
+	}
+}
+
+// pathLatency is the delay applied to requests, keyed by request path.
+type pathLatency struct {
+	mutex sync.Mutex
+	paths map[string]time.Duration
+}
+
+func (l *pathLatency) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		l.mutex.Lock()
+		d := l.paths[c.Request().URL().Path()]
+		l.mutex.Unlock()
+
+		if d > 0 {
+			time.Sleep(d)
+		}
+		return next(c)
+	}
+}
+
+// SetPathLatency causes the server to wait for d before handling requests for
+// path (an exact request path such as "/users/42", not a route pattern). This
+// allows different endpoints to respond at different speeds. A zero d removes
+// the latency for path. Path latency is in addition to any latency set via
+// SetLatency.
+func (t *Techo) SetPathLatency(path string, d time.Duration) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pathLatency == nil {
+		t.pathLatency = &pathLatency{paths: map[string]time.Duration{}}
+		t.Use(t.pathLatency.middleware)
+	}
+
+	t.pathLatency.mutex.Lock()
+	defer t.pathLatency.mutex.Unlock()
+	if d <= 0 {
+		delete(t.pathLatency.paths, path)
+		return
+	}
+	t.pathLatency.paths[path] = d
+}
+
+// jitter is a random delay applied to each request before it is handled.
+type jitter struct {
+	mutex sync.Mutex
+	min   time.Duration
+	max   time.Duration
+	rnd   *rand.Rand
+}
+
+func (j *jitter) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		j.mutex.Lock()
+		d := j.next()
+		j.mutex.Unlock()
+
+		if d > 0 {
+			time.Sleep(d)
+		}
+		return next(c)
+	}
+}
+
+// next returns the next random delay in [min, max]. The caller must hold
+// the mutex.
+func (j *jitter) next() time.Duration {
+
+	if j.max <= j.min {
+		return j.min
+	}
+	return j.min + time.Duration(j.rnd.Int63n(int64(j.max-j.min)+1))
+}
+
+// getJitter returns t's jitter, installing the jitter middleware if necessary.
+func (t *Techo) getJitter() *jitter {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.jitter == nil {
+		t.jitter = &jitter{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+		t.Use(t.jitter.middleware)
+	}
+	return t.jitter
+}
+
+// SetLatencyJitter causes the server to wait for a random duration in the
+// range [min, max] before handling each request. This is useful for testing
+// client behavior over a distribution of response times. Use SetLatencySeed
+// to make the sequence of delays deterministic. Jitter is in addition to any
+// latency set via SetLatency or SetPathLatency. SetLatencyJitter(0, 0) removes
+// the jitter.
+func (t *Techo) SetLatencyJitter(min, max time.Duration) {
+
+	j := t.getJitter()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.min = min
+	j.max = max
+}
+
+// SetLatencySeed seeds the random number generator used by SetLatencyJitter.
+func (t *Techo) SetLatencySeed(seed int64) {
+
+	j := t.getJitter()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.rnd = rand.New(rand.NewSource(seed))
+}
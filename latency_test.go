@@ -0,0 +1,119 @@
+package techo
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLatency(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	latency := time.Millisecond * 100
+	te.SetLatency(latency)
+
+	start := time.Now()
+	resp, err := http.Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.True(t, time.Since(start) >= latency)
+
+	te.ClearLatency()
+
+	start = time.Now()
+	resp, err = http.Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.True(t, time.Since(start) < latency)
+}
+
+func TestSetPathLatency(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/slow", http.StatusOK, "slow")
+	te.Respond(echo.GET, "/fast", http.StatusOK, "fast")
+
+	latency := time.Millisecond * 200
+	te.SetPathLatency("/slow", latency)
+	te.SetPathLatency("/fast", 0)
+
+	elapsed := func(path string) time.Duration {
+		start := time.Now()
+		resp, err := http.Get(te.AbsURL(path))
+		require.Nil(t, err)
+		resp.Body.Close()
+		return time.Since(start)
+	}
+
+	slow := elapsed("/slow")
+	fast := elapsed("/fast")
+	assert.True(t, slow >= latency)
+	assert.True(t, fast < latency)
+	assert.True(t, slow-fast > latency/2)
+}
+
+func TestSetLatencyJitter(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	min, max := time.Millisecond*20, time.Millisecond*300
+	const seed = 42
+	te.SetLatencyJitter(min, max)
+	te.SetLatencySeed(seed)
+
+	// The expected delays are those generated by the same seed. Only a lower
+	// bound is checked, as the upper bound depends on the machine's load.
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < 2; i++ {
+		want := min + time.Duration(rnd.Int63n(int64(max-min)+1))
+
+		start := time.Now()
+		resp, err := http.Get(te.AbsURL("/hello"))
+		require.Nil(t, err)
+		resp.Body.Close()
+		got := time.Since(start)
+
+		assert.True(t, got >= want, "request %v: want delay %v, got %v", i, want, got)
+	}
+
+	te.SetLatencyJitter(0, 0)
+	j := te.getJitter()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	assert.Equal(t, time.Duration(0), j.next())
+}
+
+func TestJitterNext(t *testing.T) {
+
+	min, max := time.Millisecond*20, time.Millisecond*300
+	const seed = 42
+
+	// Jitters with the same seed produce the same sequence of delays.
+	j1 := &jitter{min: min, max: max, rnd: rand.New(rand.NewSource(seed))}
+	j2 := &jitter{min: min, max: max, rnd: rand.New(rand.NewSource(seed))}
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < 100; i++ {
+		want := min + time.Duration(rnd.Int63n(int64(max-min)+1))
+		got := j1.next()
+		assert.Equal(t, want, got, "draw %v", i)
+		assert.Equal(t, got, j2.next(), "draw %v", i)
+		assert.True(t, got >= min && got <= max, "draw %v: %v out of range", i, got)
+	}
+
+	// If max does not exceed min, the delay is always min.
+	j := &jitter{min: min, max: min, rnd: rand.New(rand.NewSource(seed))}
+	assert.Equal(t, min, j.next())
+	j = &jitter{rnd: rand.New(rand.NewSource(seed))}
+	assert.Equal(t, time.Duration(0), j.next())
+}
@@ -0,0 +1,277 @@
+package techo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// RequestMatcher specifies criteria that a request must meet to match. Each
+// criterion is optional: a zero-valued field matches any request.
+type RequestMatcher struct {
+	// Method is the HTTP method, e.g. "POST".
+	Method string
+	// Path is matched against the request path, e.g. regexp.MustCompile("^/users/[0-9]+$").
+	Path *regexp.Regexp
+	// Header is the headers (and values) that the request must have.
+	Header map[string]string
+	// BodyContains is a substring that the request body must contain.
+	BodyContains string
+}
+
+// Match returns true if req (with the supplied body) meets the criteria of m.
+func (m RequestMatcher) Match(req *http.Request, body []byte) bool {
+
+	if m.Method != "" && m.Method != req.Method {
+		return false
+	}
+
+	if m.Path != nil && !m.Path.MatchString(req.URL.Path) {
+		return false
+	}
+
+	for name, val := range m.Header {
+		if req.Header.Get(name) != val {
+			return false
+		}
+	}
+
+	if m.BodyContains != "" && !bytes.Contains(body, []byte(m.BodyContains)) {
+		return false
+	}
+
+	return true
+}
+
+// String returns a description of m, for use in messages.
+func (m RequestMatcher) String() string {
+
+	var parts []string
+	method := m.Method
+	if method == "" {
+		method = "*"
+	}
+	parts = append(parts, method)
+
+	if m.Path != nil {
+		parts = append(parts, fmt.Sprintf("path =~ %q", m.Path.String()))
+	}
+
+	names := make([]string, 0, len(m.Header))
+	for name := range m.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%v: %q", name, m.Header[name]))
+	}
+
+	if m.BodyContains != "" {
+		parts = append(parts, fmt.Sprintf("body contains %q", m.BodyContains))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Stub is a response stub for requests meeting the criteria of a
+// RequestMatcher. See Techo.When.
+type Stub struct {
+	m       *matching
+	matcher RequestMatcher
+	ready   bool // true once a response has been set
+	status  int
+	body    string
+}
+
+// Respond sets the response to requests matching the stub.
+func (s *Stub) Respond(status int, body string) {
+	s.m.mutex.Lock()
+	defer s.m.mutex.Unlock()
+	s.status = status
+	s.body = body
+	s.ready = true
+}
+
+// expectation is a request expected to be made a number of times.
+type expectation struct {
+	matcher RequestMatcher
+	times   int
+	count   int
+}
+
+// matchLatency is a delay applied to requests that match a RequestMatcher.
+type matchLatency struct {
+	matcher RequestMatcher
+	key     string // matcher.String(), which identifies equivalent matchers
+	d       time.Duration
+}
+
+// matching holds the request matcher based state: it counts the requests that
+// match each expectation, delays requests that match a latency, and responds
+// to requests that match a stub, in preference to routes. Expectations are
+// counted before stubs are consulted, so that requests handled by a stub are
+// also counted.
+type matching struct {
+	mutex        sync.Mutex
+	stubs        []*Stub
+	expectations []*expectation
+	latencies    []*matchLatency
+}
+
+func (m *matching) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		req := stdRequest(c)
+		body, err := bufferBody(req)
+		if err != nil {
+			return err
+		}
+
+		m.mutex.Lock()
+		for _, exp := range m.expectations {
+			if exp.matcher.Match(req, body) {
+				exp.count++
+			}
+		}
+
+		var delay time.Duration
+		for _, l := range m.latencies {
+			if l.matcher.Match(req, body) {
+				delay = l.d
+				break
+			}
+		}
+
+		var match *Stub
+		for _, stub := range m.stubs {
+			if stub.ready && stub.matcher.Match(req, body) {
+				match = stub
+				break
+			}
+		}
+		var status int
+		var resp string
+		if match != nil {
+			status, resp = match.status, match.body
+		}
+		m.mutex.Unlock()
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+
+		if match == nil {
+			return next(c)
+		}
+		return c.String(status, resp)
+	}
+}
+
+// When registers a stub for requests that meet the criteria of matcher. The
+// stub takes effect once its response is set via Respond, for example:
+//
+//	te.When(techo.RequestMatcher{Method: echo.GET, Header: map[string]string{"X-Version": "2"}}).
+//		Respond(http.StatusOK, "v2")
+//
+// Stubs are consulted before routes, in the order they were registered: the
+// first matching stub wins.
+func (t *Techo) When(matcher RequestMatcher) *Stub {
+
+	m := t.getMatching()
+	stub := &Stub{m: m, matcher: matcher}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stubs = append(m.stubs, stub)
+	return stub
+}
+
+// getMatching returns t's matching, installing the matching middleware if
+// necessary.
+func (t *Techo) getMatching() *matching {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.matching == nil {
+		t.matching = &matching{}
+		t.Use(t.matching.middleware)
+	}
+	return t.matching
+}
+
+// Expect registers an expectation that requests meeting the criteria of
+// matcher are made exactly the supplied number of times. Use
+// VerifyExpectations to check whether the expectations were met. Note that
+// Expect does not register a response: combine with When or a route.
+func (t *Techo) Expect(matcher RequestMatcher, times int) {
+
+	m := t.getMatching()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.expectations = append(m.expectations, &expectation{matcher: matcher, times: times})
+}
+
+// VerifyExpectations returns an error describing each expectation registered
+// via Expect that was not met, or nil if all expectations were met.
+func (t *Techo) VerifyExpectations() error {
+
+	t.mutex.Lock()
+	m := t.matching
+	t.mutex.Unlock()
+
+	if m == nil {
+		return nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var unmet []string
+	for _, exp := range m.expectations {
+		if exp.count != exp.times {
+			unmet = append(unmet, fmt.Sprintf("%v: expected %v call(s), got %v", exp.matcher, exp.times, exp.count))
+		}
+	}
+
+	if len(unmet) > 0 {
+		return fmt.Errorf("techo: %v unmet expectation(s): %v", len(unmet), strings.Join(unmet, "; "))
+	}
+	return nil
+}
+
+// SetLatencyForMatch causes the server to wait for d before handling requests
+// that meet the criteria of matcher. This is useful for simulating one slow
+// endpoint among many. If several matchers match a request, the first
+// registered wins. Calling SetLatencyForMatch again with an equivalent matcher
+// (one with the same String representation) replaces its latency, and a zero d
+// removes it. Match latency is in addition to any latency set via SetLatency or
+// SetPathLatency.
+func (t *Techo) SetLatencyForMatch(matcher RequestMatcher, d time.Duration) {
+
+	key := matcher.String()
+	m := t.getMatching()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, l := range m.latencies {
+		if l.key == key {
+			if d <= 0 {
+				m.latencies = append(m.latencies[:i], m.latencies[i+1:]...)
+				return
+			}
+			l.d = d
+			return
+		}
+	}
+
+	if d > 0 {
+		m.latencies = append(m.latencies, &matchLatency{matcher: matcher, key: key, d: d})
+	}
+}
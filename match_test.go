@@ -0,0 +1,168 @@
+package techo
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhen(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/users/1", http.StatusOK, "route")
+
+	users := regexp.MustCompile("^/users/[0-9]+$")
+	te.When(RequestMatcher{Method: echo.GET, Path: users, Header: map[string]string{"X-Version": "2"}}).
+		Respond(http.StatusOK, "v2")
+	te.When(RequestMatcher{Method: echo.GET, Path: users, Header: map[string]string{"X-Version": "3"}}).
+		Respond(http.StatusAccepted, "v3")
+	te.When(RequestMatcher{Method: echo.POST, BodyContains: "widget"}).
+		Respond(http.StatusCreated, "created widget")
+	te.When(RequestMatcher{Method: echo.DELETE}) // No response set, so inactive
+
+	get := func(path, version string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, te.AbsURL(path), nil)
+		require.Nil(t, err)
+		if version != "" {
+			req.Header.Set("X-Version", version)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		status, body, err := readResponse(resp)
+		require.Nil(t, err)
+		return status, string(body)
+	}
+
+	status, body := get("/users/42", "2")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "v2", body)
+
+	status, body = get("/users/42", "3")
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "v3", body)
+
+	// No stub matches, so the route responds.
+	status, body = get("/users/1", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "route", body)
+
+	status, b, err := te.Post("/things", "application/json", strings.NewReader(`{"name":"widget"}`))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created widget", string(b))
+
+	status, _, err = te.Post("/things", "application/json", strings.NewReader(`{"name":"gadget"}`))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestExpect(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/a", http.StatusOK, "a")
+	te.Respond(echo.POST, "/b", http.StatusOK, "b")
+
+	require.Nil(t, te.VerifyExpectations(), "no expectations")
+
+	te.Expect(RequestMatcher{Method: echo.GET, Path: regexp.MustCompile("^/a$")}, 2)
+	te.Expect(RequestMatcher{Method: echo.POST, BodyContains: "widget"}, 1)
+
+	_, _, err := te.Get("/a")
+	require.Nil(t, err)
+	_, _, err = te.Post("/b", "text/plain", strings.NewReader("a widget"))
+	require.Nil(t, err)
+
+	err = te.VerifyExpectations()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), `GET path =~ "^/a$": expected 2 call(s), got 1`)
+	assert.NotContains(t, err.Error(), "widget")
+
+	_, _, err = te.Get("/a")
+	require.Nil(t, err)
+	require.Nil(t, te.VerifyExpectations())
+
+	_, _, err = te.Get("/a")
+	require.Nil(t, err)
+	require.NotNil(t, te.VerifyExpectations(), "too many calls")
+}
+
+func TestExpectWithStub(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	// The stub is registered before the expectation, but requests it handles
+	// are still counted.
+	matcher := RequestMatcher{Method: echo.GET, Path: regexp.MustCompile("^/stubbed$")}
+	te.When(matcher).Respond(http.StatusOK, "stubbed")
+	te.Expect(matcher, 1)
+
+	status, body, err := te.Get("/stubbed")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "stubbed", string(body))
+	require.Nil(t, te.VerifyExpectations())
+}
+
+func TestSetLatencyForMatch(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/slow", http.StatusOK, "get")
+	te.Respond(echo.POST, "/slow", http.StatusOK, "post")
+
+	latency := time.Millisecond * 200
+	matcher := RequestMatcher{
+		Method: echo.POST,
+		Path:   regexp.MustCompile("^/slow$"),
+		Header: map[string]string{"X-Slow": "true"},
+	}
+	te.SetLatencyForMatch(matcher, latency)
+
+	elapsed := func(method string, slow bool) time.Duration {
+		req, err := http.NewRequest(method, te.AbsURL("/slow"), nil)
+		require.Nil(t, err)
+		if slow {
+			req.Header.Set("X-Slow", "true")
+		}
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return time.Since(start)
+	}
+
+	assert.True(t, elapsed(echo.POST, true) >= latency)
+	assert.True(t, elapsed(echo.GET, true) < latency)
+	assert.True(t, elapsed(echo.POST, false) < latency)
+
+	// An equivalent matcher, built separately, replaces the existing entry.
+	equivalent := func() RequestMatcher {
+		return RequestMatcher{
+			Method: echo.POST,
+			Path:   regexp.MustCompile("^/slow$"),
+			Header: map[string]string{"X-Slow": "true"},
+		}
+	}
+	te.SetLatencyForMatch(equivalent(), latency*2)
+	m := te.getMatching()
+	m.mutex.Lock()
+	require.Equal(t, 1, len(m.latencies))
+	assert.Equal(t, latency*2, m.latencies[0].d)
+	m.mutex.Unlock()
+
+	te.SetLatencyForMatch(equivalent(), 0)
+	assert.True(t, elapsed(echo.POST, true) < latency)
+	m.mutex.Lock()
+	assert.Empty(t, m.latencies)
+	m.mutex.Unlock()
+}
@@ -0,0 +1,41 @@
+package techo
+
+import (
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+)
+
+// notFound replaces echo's 404 Not Found error with a different status. The
+// status is stored atomically so that it can be changed at any time.
+type notFound struct {
+	status int32
+}
+
+func (n *notFound) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		err := next(c)
+		if err == echo.ErrNotFound {
+			return echo.NewHTTPError(int(atomic.LoadInt32(&n.status)))
+		}
+		return err
+	}
+}
+
+// SetNotFoundStatus causes requests that do not match a route to receive the
+// supplied status instead of 404 Not Found. For example, this can simulate a
+// gateway that responds 503 Service Unavailable for unknown paths. Note that
+// this also applies to any handler that returns echo.ErrNotFound, such as a
+// static file handler for a missing file.
+func (t *Techo) SetNotFoundStatus(status int) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.notFound == nil {
+		t.notFound = &notFound{}
+		t.Use(t.notFound.middleware)
+	}
+	atomic.StoreInt32(&t.notFound.status, int32(status))
+}
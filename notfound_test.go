@@ -0,0 +1,31 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetNotFoundStatus(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	te.SetNotFoundStatus(http.StatusServiceUnavailable)
+
+	status, _, err := te.Get("/nope")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	status, _, err = te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	te.SetNotFoundStatus(http.StatusNotFound)
+	status, _, err = te.Get("/nope")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,91 @@
+package techo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// autoOptions is middleware that responds to OPTIONS requests for any path
+// that has routes registered, unless an OPTIONS route is registered for it.
+func autoOptions(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		if c.Request().Method() != echo.OPTIONS {
+			return next(c)
+		}
+
+		path := c.Request().URL().Path()
+		seen := map[string]bool{}
+		var methods []string
+		for _, r := range c.Echo().Routes() {
+			if seen[r.Method] || !routeMatches(r.Path, path) {
+				continue
+			}
+			if r.Method == echo.OPTIONS {
+				// An explicit OPTIONS route takes precedence.
+				return next(c)
+			}
+			seen[r.Method] = true
+			methods = append(methods, r.Method)
+		}
+
+		if len(methods) == 0 {
+			return next(c)
+		}
+
+		sort.Strings(methods)
+		c.Response().Header().Set("Allow", strings.Join(methods, ", "))
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// routeMatches returns true if the request path matches the route path
+// pattern, which may contain named params (":id") and a trailing wildcard
+// ("*"), which may follow a prefix (as registered by Static, e.g. "/files*").
+func routeMatches(pattern, path string) bool {
+
+	patternSegs := strings.Split(pattern, "/")
+	pathSegs := strings.Split(path, "/")
+
+	for i, seg := range patternSegs {
+		if seg == "*" && i == len(patternSegs)-1 {
+			return len(pathSegs) >= i
+		}
+		if strings.HasSuffix(seg, "*") && i == len(patternSegs)-1 {
+			return i < len(pathSegs) && strings.HasPrefix(pathSegs[i], strings.TrimSuffix(seg, "*"))
+		}
+		if i >= len(pathSegs) {
+			return false
+		}
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			if pathSegs[i] == "" {
+				return false
+			}
+		case seg != pathSegs[i]:
+			return false
+		}
+	}
+	return len(patternSegs) == len(pathSegs)
+}
+
+// AutoOptions installs middleware that responds to OPTIONS requests for any
+// registered path with 204 No Content, listing the methods registered for
+// the path in the Allow header, e.g. "Allow: GET, POST". Otherwise, such
+// requests receive 405 Method Not Allowed. An OPTIONS route registered for a
+// path takes precedence. It is safe to call AutoOptions multiple times.
+func (t *Techo) AutoOptions() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.autoOptions {
+		return
+	}
+
+	t.autoOptions = true
+	t.Use(autoOptions)
+}
@@ -0,0 +1,86 @@
+package techo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoOptions(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.AutoOptions()
+	te.AutoOptions()
+
+	te.Respond(echo.GET, "/x", http.StatusOK, "get")
+	te.Respond(echo.POST, "/x", http.StatusOK, "post")
+	te.Respond(echo.PUT, "/users/:id", http.StatusOK, "put")
+	te.Respond(echo.GET, "/files/*", http.StatusOK, "file")
+	te.Respond(echo.GET, "/custom", http.StatusOK, "get")
+	te.Respond(echo.OPTIONS, "/custom", http.StatusTeapot, "custom")
+
+	options := func(path string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, te.AbsURL(path), nil)
+		require.Nil(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := options("/x")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "GET, POST", resp.Header.Get("Allow"))
+
+	resp = options("/users/42")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "PUT", resp.Header.Get("Allow"))
+
+	resp = options("/files/a/b.txt")
+	assert.Equal(t, "GET", resp.Header.Get("Allow"))
+
+	resp = options("/custom")
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	resp = options("/nope")
+	assert.NotEqual(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Allow"))
+
+	// Other methods are unaffected.
+	status, body, err := te.Get("/x")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "get", string(body))
+}
+
+func TestRouteMatches(t *testing.T) {
+
+	testCases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/x", "/x", true},
+		{"/x", "/y", false},
+		{"/x", "/x/y", false},
+		{"/users/:id", "/users/42", true},
+		{"/users/:id", "/users/", false},
+		{"/users/:id", "/users/42/posts", false},
+		{"/files/*", "/files/", true},
+		{"/files/*", "/files/a/b", true},
+		{"/files/*", "/other/a", false},
+		{"/*", "/anything/at/all", true},
+		{"/files*", "/files/a/b", true},
+		{"/files*", "/filesystem", true},
+		{"/files*", "/other/a", false},
+		{"/files*", "/", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, routeMatches(tc.pattern, tc.path), "%v %v", tc.pattern, tc.path)
+	}
+}
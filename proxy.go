@@ -0,0 +1,28 @@
+package techo
+
+import (
+	"fmt"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/labstack/echo/engine/standard"
+)
+
+// Proxy registers a catch-all handler that forwards requests (for any method
+// and path) to upstream, e.g. "http://localhost:8080", and relays the response.
+// Requests are still recorded if recording is enabled, which makes it possible
+// to inspect real traffic. Routes registered explicitly take precedence over
+// the proxy. An error is returned if upstream is not an absolute URL.
+func (t *Techo) Proxy(upstream string) error {
+
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("techo: proxy upstream is not an absolute URL: %q", upstream)
+	}
+
+	t.Any("/*", standard.WrapHandler(httputil.NewSingleHostReverseProxy(u)))
+	return nil
+}
@@ -0,0 +1,48 @@
+package techo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy(t *testing.T) {
+
+	upstream := New()
+	defer upstream.Stop()
+	upstream.Respond(echo.GET, "/hello", http.StatusOK, "hello from upstream")
+	upstream.EchoRequest("/echo")
+
+	te := New()
+	defer te.Stop()
+	te.EnableRecording()
+	require.Nil(t, te.Proxy(upstream.URL))
+	te.Respond(echo.GET, "/local", http.StatusOK, "local")
+
+	status, body, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello from upstream", string(body))
+
+	status, body, err = te.Post("/echo?x=1", "text/plain", strings.NewReader("payload"))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(body), `"body":"payload"`)
+
+	// Explicit routes take precedence.
+	status, body, err = te.Get("/local")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "local", string(body))
+
+	reqs := te.Requests()
+	require.Equal(t, 3, len(reqs))
+	assert.Equal(t, "/echo", reqs[1].URL.Path)
+	assert.Equal(t, "payload", string(reqs[1].Body))
+
+	require.NotNil(t, te.Proxy("not-a-url"))
+}
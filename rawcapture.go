@@ -0,0 +1,85 @@
+package techo
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+)
+
+// rawCapture records the raw bytes read from each connection.
+type rawCapture struct {
+	mutex sync.Mutex
+	conns [][]byte
+}
+
+// wrap returns conn wrapped so that the bytes read from it are captured. TLS
+// connections are returned as-is, as the http server requires a *tls.Conn.
+func (rc *rawCapture) wrap(conn net.Conn) net.Conn {
+
+	if _, ok := conn.(*tls.Conn); ok {
+		return conn
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.conns = append(rc.conns, nil)
+	return &captureConn{Conn: conn, rc: rc, i: len(rc.conns) - 1}
+}
+
+// captureConn is a net.Conn that appends the bytes read from it to its
+// entry in rc.
+type captureConn struct {
+	net.Conn
+	rc *rawCapture
+	i  int
+}
+
+func (c *captureConn) Read(b []byte) (int, error) {
+
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.rc.mutex.Lock()
+		c.rc.conns[c.i] = append(c.rc.conns[c.i], b[:n]...)
+		c.rc.mutex.Unlock()
+	}
+	return n, err
+}
+
+// EnableRawCapture causes the raw bytes received on each connection accepted
+// from now on to be captured, exactly as read off the wire, before they are
+// parsed. This is useful for protocol-level testing, e.g. to verify a client's
+// request line and header formatting. The captured bytes are available via
+// RawRequests. Capture is not supported for TLS servers, as the bytes on the
+// wire are encrypted. It is safe to call EnableRawCapture multiple times.
+func (t *Techo) EnableRawCapture() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.rawCapture == nil {
+		t.rawCapture = &rawCapture{}
+	}
+}
+
+// RawRequests returns a copy of the raw bytes captured for each connection,
+// in the order the connections were accepted. Each entry holds everything
+// received on the connection so far, which may be several requests if the
+// connection was reused. It returns nil if raw capture has not been enabled.
+func (t *Techo) RawRequests() [][]byte {
+
+	t.mutex.Lock()
+	rc := t.rawCapture
+	t.mutex.Unlock()
+
+	if rc == nil {
+		return nil
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	raw := make([][]byte, len(rc.conns))
+	for i, b := range rc.conns {
+		raw[i] = append([]byte(nil), b...)
+	}
+	return raw
+}
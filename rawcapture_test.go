@@ -0,0 +1,50 @@
+package techo
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableRawCapture(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	assert.Nil(t, te.RawRequests(), "raw capture not enabled")
+
+	te.EnableRawCapture()
+	te.EnableRawCapture()
+	te.Respond(echo.GET, "/raw", http.StatusOK, "ok")
+	assert.Empty(t, te.RawRequests())
+
+	// The header name casing and spacing would be normalized by the parser.
+	raw := "GET /raw?a=1 HTTP/1.1\r\nHost: example.com\r\nx-odd-CASE:   spaced  \r\n\r\n"
+
+	conn, err := net.Dial("tcp", te.Addr.String())
+	require.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(raw))
+	require.Nil(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reqs := te.RawRequests()
+	require.Equal(t, 1, len(reqs))
+	assert.Equal(t, raw, string(reqs[0]))
+
+	// A request on a new connection is captured separately.
+	status, _, err := te.Get("/raw")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	reqs = te.RawRequests()
+	require.Equal(t, 2, len(reqs))
+	assert.Contains(t, string(reqs[1]), "GET /raw HTTP/1.1\r\n")
+}
@@ -0,0 +1,182 @@
+package techo
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// RecordedRequest is a request received by the server, as captured when
+// recording is enabled. See Techo.EnableRecording.
+type RecordedRequest struct {
+	// Method is the HTTP method, e.g. "POST".
+	Method string
+	// URL is the request URL, as received by the server.
+	URL *url.URL
+	// Header is a copy of the request header.
+	Header http.Header
+	// Body is the request body.
+	Body []byte
+	// Time is when the request was received.
+	Time time.Time
+	// ClientCert is the verified client certificate, if the client presented
+	// one over mutual TLS (see Config.ClientCAs).
+	ClientCert *x509.Certificate
+}
+
+// HeaderValue returns the first value of the named request header, or empty
+// string if there is no such header. The lookup is case-insensitive.
+func (r RecordedRequest) HeaderValue(name string) string {
+
+	if v := r.Header.Get(name); v != "" {
+		return v
+	}
+
+	// The header map may contain keys that are not in canonical form.
+	for k, vals := range r.Header {
+		if strings.EqualFold(k, name) && len(vals) > 0 {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
+// DecodeJSON unmarshals the recorded request body into v. An error is
+// returned if the body is empty.
+func (r RecordedRequest) DecodeJSON(v interface{}) error {
+
+	if len(r.Body) == 0 {
+		return fmt.Errorf("techo: cannot decode JSON: %v %v has empty body", r.Method, r.URL)
+	}
+	return json.Unmarshal(r.Body, v)
+}
+
+// Cookies parses and returns the cookies sent with the recorded request.
+func (r RecordedRequest) Cookies() []*http.Cookie {
+	req := &http.Request{Header: r.Header}
+	return req.Cookies()
+}
+
+// recorder captures requests into a slice of RecordedRequest.
+type recorder struct {
+	mutex    sync.Mutex
+	requests []RecordedRequest
+}
+
+// middleware returns echo middleware that records each request. The request
+// body is read in full and then restored, so that downstream handlers can
+// still read it.
+func (r *recorder) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		req := stdRequest(c)
+		body, err := bufferBody(req)
+		if err != nil {
+			return err
+		}
+
+		u := *req.URL
+		rr := RecordedRequest{
+			Method: req.Method,
+			URL:    &u,
+			Header: req.Header.Clone(),
+			Body:   body,
+			Time:   time.Now(),
+		}
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			rr.ClientCert = req.TLS.PeerCertificates[0]
+		}
+
+		r.mutex.Lock()
+		r.requests = append(r.requests, rr)
+		r.mutex.Unlock()
+
+		return next(c)
+	}
+}
+
+// bufferBody reads the body of req in full, and then restores it, so that it
+// can be read again by downstream handlers.
+func bufferBody(req *http.Request) ([]byte, error) {
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// EnableRecording installs middleware that records every request received by
+// the server. The recorded requests are available via Requests. It is safe to
+// call EnableRecording multiple times.
+func (t *Techo) EnableRecording() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.recorder != nil {
+		return
+	}
+
+	t.recorder = &recorder{}
+	t.Use(t.recorder.middleware)
+}
+
+// Requests returns a copy of the requests recorded so far, in the order they
+// were received. It returns nil if recording has not been enabled.
+func (t *Techo) Requests() []RecordedRequest {
+
+	t.mutex.Lock()
+	rec := t.recorder
+	t.mutex.Unlock()
+
+	if rec == nil {
+		return nil
+	}
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	reqs := make([]RecordedRequest, len(rec.requests))
+	copy(reqs, rec.requests)
+	return reqs
+}
+
+// ClearRecorded discards the requests recorded so far, leaving recording
+// enabled, and routes and other state intact. This is useful between the
+// assertion phases of a test. It does nothing if recording is not enabled.
+func (t *Techo) ClearRecorded() {
+
+	t.mutex.Lock()
+	rec := t.recorder
+	t.mutex.Unlock()
+
+	if rec == nil {
+		return
+	}
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	rec.requests = nil
+}
+
+// LastRequest returns the most recently recorded request. The bool return is
+// false if no request has been recorded, or if recording is not enabled.
+func (t *Techo) LastRequest() (RecordedRequest, bool) {
+
+	reqs := t.Requests()
+	if len(reqs) == 0 {
+		return RecordedRequest{}, false
+	}
+	return reqs[len(reqs)-1], true
+}
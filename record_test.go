@@ -0,0 +1,176 @@
+package techo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecording(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnableRecording()
+	te.POST("/things", func(c echo.Context) error {
+		// The handler must still be able to read the body
+		body, err := ioutil.ReadAll(c.Request().Body())
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	})
+
+	resp, err := http.Post(te.AbsURL("/things?color=red"), "text/plain", strings.NewReader("hello world"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	reqs := te.Requests()
+	require.Equal(t, 1, len(reqs))
+	assert.Equal(t, http.MethodPost, reqs[0].Method)
+	assert.Equal(t, "/things", reqs[0].URL.Path)
+	assert.Equal(t, "red", reqs[0].URL.Query().Get("color"))
+	assert.Equal(t, "text/plain", reqs[0].Header.Get("Content-Type"))
+	assert.Equal(t, "hello world", string(reqs[0].Body))
+	assert.False(t, reqs[0].Time.IsZero())
+}
+
+func TestLastRequest(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	_, ok := te.LastRequest()
+	assert.False(t, ok, "recording not enabled")
+
+	te.EnableRecording()
+	te.Respond(echo.POST, "/things", http.StatusOK, "ok")
+
+	_, ok = te.LastRequest()
+	assert.False(t, ok, "no requests yet")
+
+	_, _, err := te.Post("/things", "application/json", strings.NewReader(`{}`))
+	require.Nil(t, err)
+	_, _, err = te.Post("/things?n=2", "text/plain", strings.NewReader("hello"))
+	require.Nil(t, err)
+
+	rr, ok := te.LastRequest()
+	require.True(t, ok)
+	assert.Equal(t, "2", rr.URL.Query().Get("n"))
+	assert.Equal(t, "hello", string(rr.Body))
+}
+
+func TestClearRecorded(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.ClearRecorded() // Recording not enabled: no-op
+
+	te.EnableRecording()
+	te.Respond(echo.GET, "/things", http.StatusOK, "ok")
+
+	_, _, err := te.Get("/things?n=1")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(te.Requests()))
+
+	te.ClearRecorded()
+	assert.Empty(t, te.Requests())
+
+	// The route and recording are still in place.
+	status, _, err := te.Get("/things?n=2")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	reqs := te.Requests()
+	require.Equal(t, 1, len(reqs))
+	assert.Equal(t, "2", reqs[0].URL.Query().Get("n"))
+}
+
+func TestHeaderValue(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnableRecording()
+	te.Respond(echo.POST, "/things", http.StatusOK, "ok")
+
+	_, _, err := te.Post("/things", "application/json", strings.NewReader(`{}`))
+	require.Nil(t, err)
+
+	rr, ok := te.LastRequest()
+	require.True(t, ok)
+	assert.Equal(t, "application/json", rr.HeaderValue("Content-Type"))
+	assert.Equal(t, "application/json", rr.HeaderValue("content-type"))
+	assert.Equal(t, "application/json", rr.HeaderValue("CONTENT-TYPE"))
+	assert.Equal(t, "", rr.HeaderValue("X-Missing"))
+
+	// Non-canonical keys are also matched.
+	rr.Header = http.Header{"x-custom": []string{"value"}}
+	assert.Equal(t, "value", rr.HeaderValue("X-Custom"))
+}
+
+func TestDecodeJSON(t *testing.T) {
+
+	type thing struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	te := New()
+	defer te.Stop()
+	te.EnableRecording()
+	te.Respond(echo.POST, "/things", http.StatusOK, "ok")
+
+	_, _, err := te.Post("/things", "application/json", strings.NewReader(`{"name":"widget","count":3}`))
+	require.Nil(t, err)
+
+	rr, ok := te.LastRequest()
+	require.True(t, ok)
+	var got thing
+	require.Nil(t, rr.DecodeJSON(&got))
+	assert.Equal(t, thing{Name: "widget", Count: 3}, got)
+
+	_, _, err = te.Post("/things", "application/json", nil)
+	require.Nil(t, err)
+	rr, ok = te.LastRequest()
+	require.True(t, ok)
+	err = rr.DecodeJSON(&got)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "empty body")
+}
+
+func TestRecordedCookies(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnableRecording()
+	te.RespondSetCookie("/login", &http.Cookie{Name: "session", Value: "abc123", Path: "/"})
+	te.Respond(echo.GET, "/profile", http.StatusOK, "profile")
+
+	jar, err := cookiejar.New(nil)
+	require.Nil(t, err)
+	client := &http.Client{Jar: jar}
+
+	for _, path := range []string{"/login", "/profile"} {
+		resp, err := client.Get(te.AbsURL(path))
+		require.Nil(t, err)
+		resp.Body.Close()
+	}
+
+	reqs := te.Requests()
+	require.Equal(t, 2, len(reqs))
+	assert.Empty(t, reqs[0].Cookies())
+
+	cookies := reqs[1].Cookies()
+	require.Equal(t, 1, len(cookies))
+	assert.Equal(t, "session", cookies[0].Name)
+	assert.Equal(t, "abc123", cookies[0].Value)
+}
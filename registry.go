@@ -0,0 +1,90 @@
+package techo
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// registry tracks the live (started but not yet stopped) techo instances. It
+// holds the underlying instance rather than the Techo, so as not to prevent an
+// unstopped Techo from being garbage-collected.
+var registry = struct {
+	sync.Mutex
+	live map[*instance]struct{}
+}{live: map[*instance]struct{}{}}
+
+// register adds in to the registry of live instances.
+func register(in *instance) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.live[in] = struct{}{}
+}
+
+// unregister removes in from the registry of live instances.
+func unregister(in *instance) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.live, in)
+}
+
+// StopAll stops every live techo instance. This is useful as a catch-all cleanup,
+// e.g. in TestMain, for servers that were never stopped.
+func StopAll() {
+
+	registry.Lock()
+	live := make([]*instance, 0, len(registry.live))
+	for in := range registry.live {
+		live = append(live, in)
+	}
+	registry.Unlock()
+
+	for _, in := range live {
+		in.stop(in.shutdownTimeout)
+	}
+}
+
+// StopAllAndWait stops every live techo instance concurrently, giving in-flight
+// requests the supplied timeout to complete, and waits for all instances to
+// stop. An error is returned if any instance had in-flight requests that were
+// terminated; the error describes each such instance.
+func StopAllAndWait(timeout time.Duration) error {
+
+	registry.Lock()
+	live := make([]*instance, 0, len(registry.live))
+	for in := range registry.live {
+		live = append(live, in)
+	}
+	registry.Unlock()
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var errs []string
+	for _, in := range live {
+		wg.Add(1)
+		go func(in *instance) {
+			defer wg.Done()
+			err := in.stopAndDrain(timeout)
+			if err != nil {
+				mutex.Lock()
+				errs = append(errs, err.Error())
+				mutex.Unlock()
+			}
+		}(in)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("techo: %v of %v instance(s) did not stop cleanly: %v", len(errs), len(live), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// LiveCount returns the number of live (started but not yet stopped) techo
+// instances in the process.
+func LiveCount() int {
+	registry.Lock()
+	defer registry.Unlock()
+	return len(registry.live)
+}
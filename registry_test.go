@@ -0,0 +1,87 @@
+package techo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopAll(t *testing.T) {
+
+	var ports []int
+	for i := 0; i < 3; i++ {
+		te := New()
+		require.NotNil(t, te)
+		ports = append(ports, te.Port)
+	}
+
+	StopAll()
+
+	for _, port := range ports {
+		l, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
+		require.Nil(t, err, "port %v should be free", port)
+		l.Close()
+	}
+}
+
+func TestLiveCount(t *testing.T) {
+
+	baseline := LiveCount()
+
+	te1 := New()
+	te2 := NewTLS()
+	require.Equal(t, baseline+2, LiveCount())
+
+	te1.Stop()
+	te2.Stop()
+	require.Equal(t, baseline, LiveCount())
+}
+
+func TestStopAllAndWait(t *testing.T) {
+
+	// startSlow starts n instances, each with an in-flight request to a
+	// handler that takes d to complete.
+	startSlow := func(n int, d time.Duration) []*Techo {
+		var tes []*Techo
+		for i := 0; i < n; i++ {
+			te := New()
+			require.NotNil(t, te)
+			te.GET("/slow", func(c echo.Context) error {
+				time.Sleep(d)
+				return c.String(http.StatusOK, "slow")
+			})
+			go te.Get("/slow")
+			tes = append(tes, te)
+		}
+
+		for _, te := range tes {
+			for atomic.LoadInt64(&te.active) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		return tes
+	}
+
+	tes := startSlow(3, time.Millisecond*100)
+	start := time.Now()
+	require.Nil(t, StopAllAndWait(time.Second))
+	// The instances are stopped concurrently.
+	assert.True(t, time.Since(start) < time.Millisecond*500)
+	for _, te := range tes {
+		l, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", te.Port))
+		require.Nil(t, err, "port %v should be free", te.Port)
+		l.Close()
+	}
+
+	startSlow(2, time.Second)
+	err := StopAllAndWait(time.Millisecond * 50)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "in-flight request(s) terminated")
+}
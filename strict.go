@@ -0,0 +1,80 @@
+package techo
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+// ErrorReporter reports test failures; it is typically a testing.TB. SetStrict
+// uses it to report unexpected requests.
+type ErrorReporter interface {
+	Errorf(format string, args ...interface{})
+}
+
+// strict reports requests that did not match a route as test failures.
+type strict struct {
+	mutex sync.Mutex
+	tb    ErrorReporter
+}
+
+// middleware checks the request against the registered routes directly, rather
+// than inspecting the handler's error, so that it is unaffected by other
+// middleware that converts or handles errors (e.g. SetNotFoundStatus). A
+// request without a matching route that is nonetheless answered successfully
+// by other middleware (e.g. AutoOptions) is not reported.
+func (s *strict) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		err := next(c)
+
+		req := c.Request()
+		if routeExists(c.Echo(), req.Method(), req.URL().Path()) {
+			return err
+		}
+		if err == nil && c.Response().Committed() && c.Response().Status() < http.StatusBadRequest {
+			return err
+		}
+
+		s.mutex.Lock()
+		tb := s.tb
+		s.mutex.Unlock()
+
+		tb.Errorf("techo: unexpected request: %v %v", req.Method(), req.URL().Path())
+		return err
+	}
+}
+
+// routeExists returns true if a route is registered on e for method and path.
+func routeExists(e *echo.Echo, method, path string) bool {
+
+	for _, r := range e.Routes() {
+		if r.Method == method && routeMatches(r.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStrict causes any request that does not match a registered route (either
+// because no route exists for the path, or because no route exists for the
+// method) to be reported as a test failure via tb.Errorf, where tb is typically
+// a testing.TB. The request still receives the usual 404 Not Found or 405
+// Method Not Allowed response. This turns requests that were not set up into
+// immediate test failures. Calling SetStrict again replaces tb. Note that Reset
+// discards strict mode.
+func (t *Techo) SetStrict(tb ErrorReporter) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.strict == nil {
+		t.strict = &strict{}
+		t.Use(t.strict.middleware)
+	}
+
+	t.strict.mutex.Lock()
+	defer t.strict.mutex.Unlock()
+	t.strict.tb = tb
+}
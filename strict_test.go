@@ -0,0 +1,182 @@
+package techo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB is a testing.TB that captures errors (and fatal errors, and cleanup
+// functions) instead of acting on them.
+type fakeTB struct {
+	testing.TB
+	mutex    sync.Mutex
+	errors   []string
+	fatals   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.fatals = append(f.fatals, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Errors() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.errors...)
+}
+
+func TestSetStrict(t *testing.T) {
+
+	var _ ErrorReporter = testing.TB(nil)
+
+	te := New()
+	defer te.Stop()
+
+	tb := &fakeTB{TB: t}
+	te.SetStrict(tb)
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	status, _, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, tb.Errors())
+
+	status, _, err = te.Get("/nope/not/here")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	errs := tb.Errors()
+	require.Equal(t, 1, len(errs))
+	assert.Contains(t, errs[0], "GET /nope/not/here")
+
+	// A registered path with the wrong method is also unexpected.
+	status, _, err = te.Post("/hello", "text/plain", nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	errs = tb.Errors()
+	require.Equal(t, 2, len(errs))
+	assert.Contains(t, errs[1], "POST /hello")
+
+	te.Reset()
+	status, _, err = te.Get("/nope")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, 2, len(tb.Errors()), "Reset discards strict mode")
+}
+
+func TestSetStrictWithProxy(t *testing.T) {
+
+	upstream := New()
+	defer upstream.Stop()
+	upstream.Respond(echo.GET, "/upstream", http.StatusOK, "from upstream")
+
+	te := New()
+	defer te.Stop()
+
+	tb := &fakeTB{TB: t}
+	te.SetStrict(tb)
+	require.Nil(t, te.Proxy(upstream.URL))
+
+	// The proxy route is not displaced by strict mode.
+	status, body, err := te.Get("/upstream")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "from upstream", string(body))
+	assert.Empty(t, tb.Errors())
+}
+
+func TestSetStrictWithNotFoundStatus(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		strictFirst bool
+	}{
+		{"strict_first", true},
+		{"not_found_first", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			te := New()
+			defer te.Stop()
+			te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+			tb := &fakeTB{TB: t}
+			if tc.strictFirst {
+				te.SetStrict(tb)
+				te.SetNotFoundStatus(http.StatusServiceUnavailable)
+			} else {
+				te.SetNotFoundStatus(http.StatusServiceUnavailable)
+				te.SetStrict(tb)
+			}
+
+			status, _, err := te.Get("/hello")
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Empty(t, tb.Errors())
+
+			status, _, err = te.Get("/nope")
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, status)
+
+			errs := tb.Errors()
+			require.Equal(t, 1, len(errs))
+			assert.Contains(t, errs[0], "GET /nope")
+		})
+	}
+}
+
+func TestSetStrictWithOtherRoutes(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "techo-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	te := New()
+	defer te.Stop()
+
+	tb := &fakeTB{TB: t}
+	te.SetStrict(tb)
+	te.AutoOptions()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	require.Nil(t, te.StaticE("/fixtures", dir))
+
+	// OPTIONS answered by AutoOptions is not unexpected.
+	req, err := http.NewRequest(http.MethodOptions, te.AbsURL("/hello"), nil)
+	require.Nil(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Nor is a path under a Static prefix, even if the file is missing.
+	_, _, err = te.Get("/fixtures/nope.json")
+	require.Nil(t, err)
+	assert.Empty(t, tb.Errors())
+}
@@ -0,0 +1,443 @@
+package techo
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// Respond registers a handler for method and path that simply responds with
+// the supplied status code and body. The registered route is returned.
+// For example:
+//
+//	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+func (t *Techo) Respond(method, path string, status int, body string) *echo.Route {
+
+	t.Match([]string{method}, path, func(c echo.Context) error {
+		return c.String(status, body)
+	})
+	return t.route(method, path)
+}
+
+// RespondJSON registers a handler for method and path that responds with the
+// supplied status code and the JSON encoding of v. The value is marshaled once,
+// at registration time: if marshaling fails, the error is returned immediately
+// and no handler is registered.
+func (t *Techo) RespondJSON(method, path string, status int, v interface{}) error {
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	t.Match([]string{method}, path, func(c echo.Context) error {
+		return c.JSONBlob(status, b)
+	})
+	return nil
+}
+
+// RespondTemplate registers a handler for method and path that responds with
+// the supplied status code and bodyTemplate, with each reference to a path
+// param, in the form {name}, replaced by the param's value in the actual
+// request. For example:
+//
+//	te.RespondTemplate(echo.GET, "/users/:id", `{"id":"{id}"}`, http.StatusOK)
+//
+// responds to GET /users/42 with {"id":"42"}. References to unknown params are
+// left as-is.
+func (t *Techo) RespondTemplate(method, path, bodyTemplate string, status int) {
+
+	t.Match([]string{method}, path, func(c echo.Context) error {
+
+		names, values := c.ParamNames(), c.ParamValues()
+		var pairs []string
+		for i, name := range names {
+			if i < len(values) {
+				pairs = append(pairs, "{"+name+"}", values[i])
+			}
+		}
+		return c.String(status, strings.NewReplacer(pairs...).Replace(bodyTemplate))
+	})
+}
+
+// RespondRedirect registers a handler at path (for any method) that redirects to
+// location with the supplied status code, which must be in the 3xx range. If it
+// is not, an error is returned and no handler is registered. Redirects can be
+// chained to build a redirect sequence, e.g. /a -> /b -> /c.
+func (t *Techo) RespondRedirect(path string, status int, location string) error {
+
+	if status < 300 || status > 399 {
+		return fmt.Errorf("techo: invalid redirect status %v for path %q", status, path)
+	}
+
+	t.Any(path, func(c echo.Context) error {
+		return c.Redirect(status, location)
+	})
+	return nil
+}
+
+// RespondStream registers a handler at path (for any method) that streams chunks
+// as the response body, flushing each chunk to the client as it is written, and
+// pausing for interval between chunks. This is useful for testing clients that
+// consume a body incrementally. If the client goes away, streaming stops.
+func (t *Techo) RespondStream(path string, chunks []string, interval time.Duration) {
+	t.Any(path, stream(chunks, interval))
+}
+
+// RespondThenReset registers a handler at path (for any method) that responds
+// 200 OK, declaring a Content-Length greater than len(partial), writes partial,
+// and then abruptly resets the underlying TCP connection. This is useful for
+// testing client handling of connections that fail mid-response: the client
+// sees an error (typically "connection reset by peer", or an unexpected EOF)
+// while reading the body. For a TLS server, the TCP connection beneath the TLS
+// connection is reset, without a TLS close_notify alert. The reset requires
+// HTTP/1.x: over HTTP/2, connections cannot be hijacked, and the handler
+// returns an error instead.
+func (t *Techo) RespondThenReset(path string, partial string) {
+
+	t.Any(path, func(c echo.Context) error {
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentLength, strconv.Itoa(len(partial)+1))
+		res.WriteHeader(http.StatusOK)
+		_, err := res.Write([]byte(partial))
+		if err != nil {
+			return err
+		}
+
+		w := stdResponseWriter(c)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			return fmt.Errorf("techo: cannot reset connection for %v: response does not support hijacking", path)
+		}
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			return err
+		}
+
+		// For TLS, close the underlying connection directly, so that there is
+		// no close_notify alert, just as for an abrupt failure.
+		conn = underlyingConn(conn)
+
+		// Discard any unsent data and send RST on close, instead of FIN.
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetLinger(0)
+		}
+		return conn.Close()
+	})
+}
+
+// underlyingConn returns the connection underlying conn, unwrapping TLS and
+// techo's own connection wrappers (see EnableRawCapture).
+func underlyingConn(conn net.Conn) net.Conn {
+
+	for {
+		switch c := conn.(type) {
+		case *tls.Conn:
+			conn = c.NetConn()
+		case *captureConn:
+			conn = c.Conn
+		default:
+			return conn
+		}
+	}
+}
+
+// RespondSlow registers a handler at path (for any method) that sends body one
+// byte at a time, flushing each byte to the client, and pausing for perByte
+// between bytes. This is useful for testing client read timeouts.
+func (t *Techo) RespondSlow(path string, body []byte, perByte time.Duration) {
+
+	chunks := make([]string, len(body))
+	for i := range body {
+		chunks[i] = string(body[i : i+1])
+	}
+	t.Any(path, stream(chunks, perByte))
+}
+
+// stream returns a handler that streams chunks as the response body, flushing
+// each chunk, and pausing for interval between chunks.
+func stream(chunks []string, interval time.Duration) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		done := stdRequest(c).Context().Done()
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+		res.WriteHeader(http.StatusOK)
+
+		for i, chunk := range chunks {
+			if i > 0 {
+				select {
+				case <-done:
+					return nil
+				case <-time.After(interval):
+				}
+			}
+
+			_, err := res.Write([]byte(chunk))
+			if err != nil {
+				return err
+			}
+			if f, ok := stdResponseWriter(c).(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return nil
+	}
+}
+
+// Write is a single write of a response body. See Techo.RespondFlush.
+type Write struct {
+	// Data is the bytes to write.
+	Data []byte
+	// Flush indicates to flush the response to the client after the write.
+	// Unflushed data is buffered by the server, and is sent when the buffer
+	// fills, when a later write is flushed, or when the response ends.
+	Flush bool
+	// Delay is how long to pause before the write.
+	Delay time.Duration
+}
+
+// RespondFlush registers a handler at path (for any method) that responds
+// 200 OK, performing each of writes in turn. Unlike RespondStream, which
+// flushes every chunk, this gives fine control over when the server flushes,
+// which is useful for testing clients that only see data once it is flushed.
+// If the client goes away, the writes stop.
+func (t *Techo) RespondFlush(path string, writes []Write) {
+
+	t.Any(path, func(c echo.Context) error {
+
+		done := stdRequest(c).Context().Done()
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+		res.WriteHeader(http.StatusOK)
+
+		for _, w := range writes {
+			if w.Delay > 0 {
+				select {
+				case <-done:
+					return nil
+				case <-time.After(w.Delay):
+				}
+			}
+
+			_, err := res.Write(w.Data)
+			if err != nil {
+				return err
+			}
+			if w.Flush {
+				if f, ok := stdResponseWriter(c).(http.Flusher); ok {
+					f.Flush()
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RespondSetCookie registers a handler at path (for any method) that responds
+// 200 OK with a Set-Cookie header for each of the supplied cookies.
+func (t *Techo) RespondSetCookie(path string, cookies ...*http.Cookie) {
+
+	t.Any(path, func(c echo.Context) error {
+		for _, cookie := range cookies {
+			c.Response().Header().Add("Set-Cookie", cookie.String())
+		}
+		return c.String(http.StatusOK, http.StatusText(http.StatusOK))
+	})
+}
+
+// RespondWithTrailers registers a handler at path (for any method) that
+// responds 200 OK with body, followed by the supplied HTTP trailers (as used by
+// gRPC-over-HTTP, for example). The trailer names are declared in the Trailer
+// header, and their values are sent after the body.
+func (t *Techo) RespondWithTrailers(path string, body string, trailers map[string]string) {
+
+	names := make([]string, 0, len(trailers))
+	for name := range trailers {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	sort.Strings(names)
+
+	t.Any(path, func(c echo.Context) error {
+
+		res := c.Response()
+		for _, name := range names {
+			res.Header().Add("Trailer", name)
+		}
+		res.Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+		res.WriteHeader(http.StatusOK)
+
+		_, err := res.Write([]byte(body))
+		if err != nil {
+			return err
+		}
+
+		// Trailer values are set after the body has been written.
+		for name, val := range trailers {
+			res.Header().Set(name, val)
+		}
+		return nil
+	})
+}
+
+// RequireValidJSON registers a handler at path (for any method) that responds
+// 200 OK if the request body is valid JSON, and 400 Bad Request otherwise
+// (including if the body is empty). This is useful for verifying that a client
+// sends well-formed JSON.
+func (t *Techo) RequireValidJSON(path string) {
+
+	t.Any(path, func(c echo.Context) error {
+
+		body, err := ioutil.ReadAll(stdRequest(c).Body)
+		if err != nil {
+			return err
+		}
+
+		if !json.Valid(body) {
+			return c.String(http.StatusBadRequest, "invalid JSON")
+		}
+		return c.String(http.StatusOK, http.StatusText(http.StatusOK))
+	})
+}
+
+// EchoedRequest is the JSON response body served by an EchoRequest endpoint.
+type EchoedRequest struct {
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Query  map[string][]string `json:"query"`
+	Header map[string][]string `json:"header"`
+	Body   string              `json:"body"`
+}
+
+// EchoRequest registers a handler at path (for any method) that responds with
+// a JSON description of the request received: its method, path, query, header
+// and body. See EchoedRequest.
+func (t *Techo) EchoRequest(path string) {
+
+	t.Any(path, func(c echo.Context) error {
+
+		req := stdRequest(c)
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, EchoedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  req.URL.Query(),
+			Header: req.Header,
+			Body:   string(body),
+		})
+	})
+}
+
+// SimulateRateLimit registers a handler at path (for any method) that simulates
+// a rate-limited endpoint. Within each window, the first allow requests receive
+// 200 OK; subsequent requests receive 429 Too Many Requests, with a Retry-After
+// header specifying the seconds remaining until the window resets.
+func (t *Techo) SimulateRateLimit(path string, allow int, window time.Duration) {
+
+	var mutex sync.Mutex
+	var windowStart time.Time
+	var count int
+
+	t.Any(path, func(c echo.Context) error {
+
+		mutex.Lock()
+		now := time.Now()
+		if now.Sub(windowStart) >= window {
+			windowStart = now
+			count = 0
+		}
+		count++
+		n := count
+		remaining := windowStart.Add(window).Sub(now)
+		mutex.Unlock()
+
+		if n <= allow {
+			return c.String(http.StatusOK, http.StatusText(http.StatusOK))
+		}
+
+		secs := int(math.Ceil(remaining.Seconds()))
+		if secs < 1 {
+			secs = 1
+		}
+		c.Response().Header().Set("Retry-After", strconv.Itoa(secs))
+		return c.String(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+	})
+}
+
+// StubResponse is a canned response. See Techo.RespondSequence.
+type StubResponse struct {
+	// Status is the response status code.
+	Status int
+	// Header is additional headers to set on the response.
+	Header http.Header
+	// Body is the response body.
+	Body string
+}
+
+// RespondSequence registers a handler for method and path that responds to the
+// i-th request with responses[i], repeating the last response once the sequence
+// is exhausted. This is useful for testing retry logic, e.g. a 503 followed by
+// a 200. If responses is empty, the handler responds with 404 Not Found.
+func (t *Techo) RespondSequence(method, path string, responses []StubResponse) {
+
+	responses = append([]StubResponse(nil), responses...)
+	var mutex sync.Mutex
+	var count int
+
+	t.Match([]string{method}, path, func(c echo.Context) error {
+
+		if len(responses) == 0 {
+			return echo.ErrNotFound
+		}
+
+		mutex.Lock()
+		i := count
+		if i < len(responses)-1 {
+			count++
+		}
+		mutex.Unlock()
+
+		resp := responses[i]
+		header := c.Response().Header()
+		for name, vals := range resp.Header {
+			for _, val := range vals {
+				header.Add(name, val)
+			}
+		}
+		return c.String(resp.Status, resp.Body)
+	})
+}
+
+// route returns the most recently registered route for method and path, or
+// nil if there is no such route.
+func (t *Techo) route(method, path string) *echo.Route {
+
+	routes := t.Echo.Routes()
+	for i := len(routes) - 1; i >= 0; i-- {
+		if routes[i].Method == method && routes[i].Path == path {
+			r := routes[i]
+			return &r
+		}
+	}
+	return nil
+}
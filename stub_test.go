@@ -0,0 +1,428 @@
+package techo
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespond(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	route := te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	require.NotNil(t, route)
+	assert.Equal(t, echo.GET, route.Method)
+	assert.Equal(t, "/hello", route.Path)
+
+	route = te.Respond(echo.POST, "/things", http.StatusCreated, "created")
+	require.NotNil(t, route)
+
+	resp, err := http.Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello world", string(body))
+
+	resp2, err := http.Post(te.AbsURL("/things"), "text/plain", strings.NewReader("thing"))
+	require.Nil(t, err)
+	defer resp2.Body.Close()
+	body, err = ioutil.ReadAll(resp2.Body)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, resp2.StatusCode)
+	assert.Equal(t, "created", string(body))
+}
+
+func TestRespondJSON(t *testing.T) {
+
+	type thing struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	te := New()
+	defer te.Stop()
+
+	want := thing{Name: "widget", Count: 3}
+	err := te.RespondJSON(echo.GET, "/thing", http.StatusOK, want)
+	require.Nil(t, err)
+
+	resp, err := http.Get(te.AbsURL("/thing"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON))
+
+	got := thing{}
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	require.Nil(t, err)
+	assert.Equal(t, want, got)
+
+	// A value that can't be marshaled
+	err = te.RespondJSON(echo.GET, "/bad", http.StatusOK, make(chan int))
+	require.NotNil(t, err)
+}
+
+func TestSimulateRateLimit(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	const allow = 3
+	te.SimulateRateLimit("/limited", allow, time.Minute)
+
+	for i := 0; i < allow; i++ {
+		resp, err := http.Get(te.AbsURL("/limited"))
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := http.Get(te.AbsURL("/limited"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+}
+
+func TestRespondTemplate(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.RespondTemplate(echo.GET, "/users/:id", `{"id":"{id}"}`, http.StatusOK)
+	te.RespondTemplate(echo.GET, "/users/:id/posts/:post", "user {id}, post {post}, {other}", http.StatusAccepted)
+
+	status, body, err := te.Get("/users/42")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(body), "42")
+	assert.Equal(t, `{"id":"42"}`, string(body))
+
+	status, body, err = te.Get("/users/7/posts/99")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "user 7, post 99, {other}", string(body))
+}
+
+func TestRespondRedirect(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	require.Nil(t, te.RespondRedirect("/a", http.StatusFound, "/b"))
+	require.Nil(t, te.RespondRedirect("/b", http.StatusMovedPermanently, te.AbsURL("/c")))
+	te.Respond(echo.GET, "/c", http.StatusOK, "landed")
+
+	resp, err := http.Get(te.AbsURL("/a"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/c", resp.Request.URL.Path)
+	assert.Equal(t, "landed", string(body))
+
+	require.NotNil(t, te.RespondRedirect("/d", http.StatusOK, "/c"))
+	require.NotNil(t, te.RespondRedirect("/d", http.StatusBadRequest, "/c"))
+}
+
+func TestRespondStream(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	chunks := []string{"one,", "two,", "three"}
+	interval := time.Millisecond * 100
+	te.RespondStream("/stream", chunks, interval)
+
+	resp, err := http.Get(te.AbsURL("/stream"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Read each chunk as it arrives, noting when it arrived.
+	var arrived []time.Time
+	for _, chunk := range chunks {
+		buf := make([]byte, len(chunk))
+		_, err = io.ReadFull(resp.Body, buf)
+		require.Nil(t, err)
+		assert.Equal(t, chunk, string(buf))
+		arrived = append(arrived, time.Now())
+	}
+
+	rest, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Empty(t, rest)
+
+	for i := 1; i < len(arrived); i++ {
+		gap := arrived[i].Sub(arrived[i-1])
+		assert.True(t, gap >= interval/2, "chunk %v arrived after only %v", i, gap)
+	}
+}
+
+func TestRespondFlush(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	delay := time.Millisecond * 300
+	te.RespondFlush("/flush", []Write{
+		{Data: []byte("one,"), Flush: true},
+		{Data: []byte("two,")}, // Not flushed, so sent with the next write
+		{Data: []byte("three"), Flush: true, Delay: delay},
+	})
+
+	start := time.Now()
+	resp, err := http.Get(te.AbsURL("/flush"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The flushed data arrives well before the response ends.
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(resp.Body, buf)
+	require.Nil(t, err)
+	assert.Equal(t, "one,", string(buf))
+	assert.True(t, time.Since(start) < delay, "flushed data should arrive before the delay")
+
+	// The unflushed data only arrives with the delayed write.
+	buf = make([]byte, 4)
+	_, err = io.ReadFull(resp.Body, buf)
+	require.Nil(t, err)
+	assert.Equal(t, "two,", string(buf))
+	assert.True(t, time.Since(start) >= delay, "unflushed data should not arrive before the delay")
+
+	rest, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "three", string(rest))
+}
+
+func TestRespondSetCookie(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.RespondSetCookie("/login",
+		&http.Cookie{Name: "session", Value: "abc123", Path: "/"},
+		&http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+
+	jar, err := cookiejar.New(nil)
+	require.Nil(t, err)
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(te.AbsURL("/login"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cookies := jar.Cookies(te.BaseURL())
+	require.Equal(t, 2, len(cookies))
+	got := map[string]string{}
+	for _, c := range cookies {
+		got[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"session": "abc123", "theme": "dark"}, got)
+}
+
+func TestRespondWithTrailers(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.RespondWithTrailers("/grpc", "hello world", map[string]string{
+		"grpc-status":  "0",
+		"Grpc-Message": "OK",
+	})
+
+	resp, err := http.Get(te.AbsURL("/grpc"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Trailers are declared up front, but their values arrive after the body.
+	_, ok := resp.Trailer["Grpc-Status"]
+	assert.True(t, ok)
+	assert.Empty(t, resp.Trailer.Get("Grpc-Status"))
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	assert.Equal(t, "OK", resp.Trailer.Get("Grpc-Message"))
+}
+
+func TestRequireValidJSON(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.RequireValidJSON("/json")
+
+	status, body, err := te.Post("/json", "application/json", strings.NewReader(`{"name": "widget",}`))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid JSON", string(body))
+
+	status, _, err = te.Post("/json", "application/json", nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _, err = te.Post("/json", "application/json", strings.NewReader(`{"name": "widget"}`))
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestEchoRequest(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EchoRequest("/echo")
+
+	req, err := http.NewRequest(http.MethodPost, te.AbsURL("/echo?color=red&color=blue"), strings.NewReader("hello world"))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Custom", "custom value")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got EchoedRequest
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "/echo", got.Path)
+	assert.Equal(t, []string{"red", "blue"}, got.Query["color"])
+	assert.Equal(t, []string{"custom value"}, got.Header["X-Custom"])
+	assert.Equal(t, []string{"text/plain"}, got.Header["Content-Type"])
+	assert.Equal(t, "hello world", got.Body)
+}
+
+func TestRespondThenReset(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.RespondThenReset("/reset", "partial data")
+
+	resp, err := http.Get(te.AbsURL("/reset"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NotNil(t, err, "expected an error reading the body")
+	assert.Equal(t, "partial data", string(body))
+}
+
+func TestRespondThenResetTLS(t *testing.T) {
+
+	te := NewTLS()
+	defer te.Stop()
+	te.RespondThenReset("/reset", "partial data")
+
+	resp, err := te.Client().Get(te.AbsURL("/reset"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NotNil(t, err, "expected an error reading the body")
+	assert.Equal(t, "partial data", string(body))
+	assert.Contains(t, err.Error(), "reset", "expected a connection reset, got: %v", err)
+}
+
+func TestRespondThenResetWithRawCapture(t *testing.T) {
+
+	for _, te := range []*Techo{New(), NewTLS()} {
+		defer te.Stop()
+		te.EnableRawCapture()
+		te.RespondThenReset("/reset", "partial data")
+
+		resp, err := te.Client().Get(te.AbsURL("/reset"))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NotNil(t, err, "expected an error reading the body")
+		assert.Equal(t, "partial data", string(body))
+		assert.Contains(t, err.Error(), "reset", "expected a connection reset, got: %v", err)
+	}
+}
+
+func TestRespondSlow(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	body := []byte("0123456789")
+	te.RespondSlow("/slow", body, time.Millisecond*50)
+
+	// The full body takes ~450ms to arrive, so this client times out.
+	client := &http.Client{Timeout: time.Millisecond * 100}
+	resp, err := client.Get(te.AbsURL("/slow"))
+	if err == nil {
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	require.NotNil(t, err)
+	netErr, ok := err.(net.Error)
+	require.True(t, ok, "expected net.Error, got %T: %v", err, err)
+	assert.True(t, netErr.Timeout())
+
+	client = &http.Client{Timeout: time.Second * 5}
+	resp, err = client.Get(te.AbsURL("/slow"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	got, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, body, got)
+}
+
+func TestRespondSequence(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.RespondSequence(echo.GET, "/flaky", []StubResponse{
+		{Status: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"1"}}, Body: "unavailable"},
+		{Status: http.StatusOK, Body: "ok"},
+	})
+	te.RespondSequence(echo.GET, "/empty", nil)
+
+	want := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusServiceUnavailable, "unavailable"},
+		{http.StatusOK, "ok"},
+		{http.StatusOK, "ok"}, // The last response is repeated
+	}
+
+	for i, w := range want {
+		resp, err := http.Get(te.AbsURL("/flaky"))
+		require.Nil(t, err)
+		status, body, err := readResponse(resp)
+		require.Nil(t, err)
+		assert.Equal(t, w.status, status, "call %v", i)
+		assert.Equal(t, w.body, string(body), "call %v", i)
+		if i == 0 {
+			assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+		} else {
+			assert.Empty(t, resp.Header.Get("Retry-After"))
+		}
+	}
+
+	status, _, err := te.Get("/empty")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -28,39 +28,118 @@ Example:
 package techo
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"log"
 	"net"
 
 	"time"
 
+	"io"
 	"io/ioutil"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"sync"
+	"sync/atomic"
 
 	"net/http"
+	"net/url"
 
 	"github.com/labstack/echo"
+	"github.com/labstack/echo/engine"
 	"github.com/labstack/echo/engine/standard"
 	"github.com/tylerb/graceful"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+// Logger is used by techo to log errors that are not otherwise returned to the
+// caller. By default, it is the standard logger, so it honors any output,
+// prefix or flags set via the log package. To silence techo (without modifying
+// the standard logger), set it to a logger that discards output:
+//
+//	techo.Logger = log.New(ioutil.Discard, "", 0)
+var Logger = log.Default()
+
 // Techo is a techo server instance.
 type Techo struct {
 	// Port is the port number the server is listening at.
 	Port int
 	// Base is the base URL (scheme + host + port), e.g. http://127.0.0.1:61241
 	URL string
-	// Addr provides access to the underlying TCP address object.
+	// Addr provides access to the underlying TCP address object. It is nil
+	// for a server listening on a unix domain socket.
 	Addr *net.TCPAddr
+	// TLS is true if the server is a TLS/HTTPS server.
+	TLS bool
 	*echo.Echo
-	srv *graceful.Server
+	*instance
+
+	listenAddr net.Addr // the listener's address, even if not TCP
+	cert       []byte
+	key        []byte
+	httpClient *http.Client
+	logOutput  io.Writer
+	healthPath string
+
+	recorder        *recorder
+	latency         *latency
+	pathLatency     *pathLatency
+	jitter          *jitter
+	faults          *faults
+	panics          *panics
+	basicAuth       *basicAuth
+	bearerAuth      *bearerAuth
+	gzipEnabled     bool
+	drainBody       bool
+	autoOptions     bool
+	bodyLimit       *bodyLimit
+	responseHeaders *responseHeaders
+	cors            *cors
+	notFound        *notFound
+	matching        *matching
+	interceptor     *interceptor
+	strict          *strict
+	errorHandler    *errorHandler
+
+	// peer is the other server started by NewDual, which shares the echo
+	// instance, or nil.
+	peer *Techo
+}
 
+// instance holds the state of the running server. It is deliberately kept
+// separate from Techo: the server goroutine, handler and registry reference
+// only the instance, so that a Techo that is dropped without being stopped
+// can still be garbage-collected, and its finalizer can remove any temporary
+// TLS files.
+type instance struct {
+	srv   *graceful.Server
+	echo  *echo.Echo // the echo instance served, kept in sync with Techo.Echo
+	mutex *sync.Mutex
+
+	socketPath   string
 	certFilePath string
 	keyFilePath  string
-	mutex        *sync.Mutex
+	tempTLSFiles bool // whether the TLS files are temp files, removed on stop
+
+	shutdownTimeout time.Duration
+	stopped         bool
+	onStop          []func()
+	counts          map[string]int  // request counts, keyed by method and path
+	cfg             *Config         // the config the server was started with
+	active          int64           // in-flight requests, accessed atomically
+	accepted        int64           // connections accepted, accessed atomically
+	written         int64           // response bytes written, accessed atomically
+	acceptDelay     int64           // delay before each accepted conn is served, accessed atomically
+	noKeepAlive     bool            // whether keep-alives are disabled, see DisableKeepAlive
+	rawCapture      *rawCapture     // captures raw connection bytes, see EnableRawCapture
+	ctx             context.Context // stops the server when done, see NewWithContext
 }
 
 // Config is the options available for staring a techo instance with techo.NewWith().
@@ -73,28 +152,281 @@ type Config struct {
 	TLSCert []byte
 	// TLSKey is the TLS private key to use.
 	TLSKey []byte
+	// TLSCertFile is the path of a TLS certificate file to use. If set, the
+	// file is served from directly (rather than being copied to a temp file),
+	// and it is not removed when the server is stopped. It takes precedence
+	// over TLSCert, and TLSKeyFile must also be set.
+	TLSCertFile string
+	// TLSKeyFile is the path of the TLS private key file to use. It must be
+	// set together with TLSCertFile.
+	TLSKeyFile string
+	// TLSConfig, if set, is the TLS configuration of a TLS server, e.g. to
+	// require a minimum TLS version. It is used directly (a clone, in fact),
+	// and no temp files are written. If it has no certificates, the cert and
+	// key specified by the other TLS fields (or the default) are added to it.
+	// It is ignored if TLS is false.
+	TLSConfig *tls.Config
+	// ClientCAs is the PEM-encoded CA certificates used to verify client
+	// certificates (mutual TLS). If set, a client certificate is verified if
+	// presented. The verified certificate is available to handlers via the
+	// request's TLS connection state, and is recorded in RecordedRequest.
+	ClientCAs [][]byte
+	// RequireClientCert indicates that clients must present a certificate
+	// signed by one of ClientCAs; connections without one are rejected.
+	RequireClientCert bool
+	// Network is the network to listen on, either "tcp" (the default) or "unix".
+	Network string
+	// SocketPath is the path of the unix domain socket to listen on, when
+	// Network is "unix". Addr is ignored in that case.
+	SocketPath string
+	// PreferredPort, if set, is the port to listen on if it is free. If it is
+	// not free, the fallback is logged, and a random port is used instead. The
+	// host is taken from Addr (if set). It is ignored if PortRange is set.
+	PreferredPort int
+	// PortRange, if set, is the inclusive range [min, max] of ports to listen on.
+	// Each port is tried in turn, and the first free port is used. The host is
+	// taken from Addr (if set).
+	PortRange [2]int
+	// HTTP2 indicates to serve HTTP/2: over TLS (negotiated via ALPN) for a TLS
+	// server, and cleartext HTTP/2 (h2c) otherwise.
+	HTTP2 bool
+	// IPv6 indicates to listen on the IPv6 loopback address [::1], instead of
+	// localhost. It has no effect if Addr is set.
+	IPv6 bool
+	// Logger receives echo's log output. By default, the output is discarded.
+	Logger io.Writer
+	// ShutdownTimeout is how long in-flight requests are given to complete
+	// when the server is stopped. If zero, the default (1ms) is used.
+	ShutdownTimeout time.Duration
+	// ReadTimeout is the maximum duration for reading an entire request,
+	// including the body. Zero means no timeout (the default).
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out writes of the
+	// response. Zero means no timeout (the default).
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration an idle keep-alive connection is
+	// kept open, waiting for the next request. If zero, ReadTimeout is used;
+	// if both are zero, there is no timeout (the default).
+	IdleTimeout time.Duration
+	// HealthPath, if set, is the path (e.g. "/healthz") of an automatically
+	// registered route that responds 200 "ok" to GET requests. Unlike other
+	// routes, it survives Reset.
+	HealthPath string
+	// TempDir is the directory in which the temporary TLS cert and key files
+	// are written. If empty, the OS temp dir is used (see os.TempDir).
+	TempDir string
+}
+
+// defaultShutdownTimeout is the time in-flight requests are given to complete
+// when the server is stopped, unless otherwise configured.
+const defaultShutdownTimeout = time.Millisecond * 1
+
+// newTecho returns a new Techo that has not yet been started, configured with
+// the settings in cfg that must be in place before the server is built.
+func newTecho(cfg *Config) *Techo {
+	t := &Techo{
+		instance: &instance{
+			mutex:           &sync.Mutex{},
+			shutdownTimeout: defaultShutdownTimeout,
+			counts:          map[string]int{},
+		},
+		logOutput:  ioutil.Discard,
+		healthPath: cfg.HealthPath,
+	}
+	if cfg.ShutdownTimeout > 0 {
+		t.shutdownTimeout = cfg.ShutdownTimeout
+	}
+	if cfg.Logger != nil {
+		t.logOutput = cfg.Logger
+	}
+	t.Echo, t.errorHandler = t.newEcho()
+	t.echo = t.Echo
+	return t
+}
+
+// newEcho returns a new echo instance, configured for t, and the errorHandler
+// installed on it.
+func (t *Techo) newEcho() (*echo.Echo, *errorHandler) {
+	e := echo.New()
+	e.SetLogOutput(t.logOutput)
+	eh := &errorHandler{}
+	e.SetHTTPErrorHandler(eh.handle)
+	if t.healthPath != "" {
+		e.GET(t.healthPath, health)
+	}
+	return e, eh
+}
+
+// errorHandler is the HTTP error handler of each echo instance. It delegates to
+// the handler set via SetHTTPErrorHandler, if any, or else to echo's default
+// handler, so that the handler can safely be changed while serving.
+type errorHandler struct {
+	mutex sync.Mutex
+	h     echo.HTTPErrorHandler
+}
+
+func (eh *errorHandler) handle(err error, c echo.Context) {
+
+	eh.mutex.Lock()
+	h := eh.h
+	eh.mutex.Unlock()
+
+	if h == nil {
+		h = c.Echo().DefaultHTTPErrorHandler
+	}
+	h(err, c)
+}
+
+// health is the handler for the route at Config.HealthPath.
+func health(c echo.Context) error {
+	return c.String(http.StatusOK, "ok")
 }
 
 // New starts a server on any available port. This value is available in the Port field.
 // In the unlikely event of an error, the error is logged, and nil is returned.
+// Use NewE if you want to handle the error yourself.
 func New() *Techo {
-	te, err := listenAndStart("localhost:")
+	te, err := NewE()
 	if err != nil {
-		log.Println(err)
+		Logger.Println(err)
 	}
 	return te
 }
 
+// NewE is like New, but returns any error that occurs while starting the server
+// instead of logging it.
+func NewE() (*Techo, error) {
+	return listenAndStart("localhost:", &Config{})
+}
+
+// TB is the subset of testing.TB used by NewT. It is declared here so that
+// techo does not import the testing package into non-test binaries.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// NewT is like New, but is tied to the lifetime of the test (or benchmark) tb,
+// typically a testing.TB: the server is stopped automatically via tb.Cleanup.
+// If the server cannot be started, tb.Fatalf is invoked.
+func NewT(tb TB) *Techo {
+	tb.Helper()
+	return newT(tb, NewE)
+}
+
+// newT implements NewT, using start to start the server.
+func newT(tb TB, start func() (*Techo, error)) *Techo {
+
+	tb.Helper()
+	te, err := start()
+	if err != nil {
+		tb.Fatalf("techo: failed to start server: %v", err)
+		return nil
+	}
+
+	tb.Cleanup(te.Stop)
+	return te
+}
+
+// NewWithContext is like New, but the server is stopped automatically when
+// ctx is done. It is still safe to call Stop explicitly. If the server is
+// restarted (see Restart) before ctx is done, it remains tied to ctx; if it is
+// restarted after ctx is done, it must be stopped explicitly.
+func NewWithContext(ctx context.Context) *Techo {
+
+	te := New()
+	if te == nil {
+		return nil
+	}
+
+	te.mutex.Lock()
+	te.ctx = ctx
+	te.mutex.Unlock()
+	te.watchContext(ctx)
+	return te
+}
+
+// watchContext stops the current server when ctx is done. The goroutine
+// references only the instance, so as not to keep the Techo alive.
+func (in *instance) watchContext(ctx context.Context) {
+
+	in.mutex.Lock()
+	stopChan := in.srv.StopChan()
+	in.mutex.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			in.stop(in.shutdownTimeout)
+		case <-stopChan:
+		}
+	}()
+}
+
+// NewWithListener starts a server that serves connections accepted by the
+// supplied listener, instead of creating its own. The Addr, Port and URL
+// fields are derived from the listener's address: if it is not a TCP address,
+// Addr is nil and Port is zero (see setAddr). The listener is closed when the
+// server is stopped.
+func NewWithListener(l net.Listener) (*Techo, error) {
+
+	cfg := &Config{}
+	t := newTecho(cfg)
+	t.setAddr(l.Addr(), "http")
+	err := t.serve(l, l.Addr().String(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewWithEcho starts a server on any available port, serving the supplied echo
+// instance (instead of a fresh one). This allows testing an application's real
+// router and middleware. Note that Reset replaces e with a fresh echo instance.
+func NewWithEcho(e *echo.Echo) (*Techo, error) {
+
+	t, err := listenAndStart("localhost:", &Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	t.Echo = e
+	t.echo = e
+	t.mutex.Unlock()
+	return t, nil
+}
+
 // NewWith starts a server using the supplied config.
 func NewWith(cfg *Config) (*Techo, error) {
-	if cfg.TLS == false {
-		if cfg.Addr == "" {
-			return listenAndStart("localhost:")
+
+	return startWith(cfg)
+}
+
+// startWith starts a server using the network and address (and, if applicable,
+// TLS settings) specified in cfg.
+func startWith(cfg *Config) (*Techo, error) {
+
+	switch cfg.Network {
+	case "", "tcp":
+	case "unix":
+		if cfg.TLS {
+			return nil, fmt.Errorf("techo: TLS is not supported for network %q", cfg.Network)
+		}
+		return listenAndStartUnix(cfg.SocketPath, cfg)
+	default:
+		return nil, fmt.Errorf("techo: unsupported network %q", cfg.Network)
+	}
+
+	addr := cfg.Addr
+	if addr == "" {
+		addr = "localhost:"
+		if cfg.IPv6 {
+			addr = "[::1]:0"
 		}
-		return listenAndStart(cfg.Addr)
 	}
 
-	// cfg.TLS == true
 	cert := defaultCert
 	key := defaultKey
 
@@ -103,161 +435,822 @@ func NewWith(cfg *Config) (*Techo, error) {
 	}
 
 	if len(cfg.TLSKey) > 0 {
-		cert = cfg.TLSKey
+		key = cfg.TLSKey
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("techo: TLSCertFile and TLSKeyFile must be set together")
 	}
 
-	if cfg.Addr == "" {
-		return listenAndStartTLS("localhost:", cert, key)
+	start := func(addr string) (*Techo, error) {
+		if cfg.TLS == false {
+			return listenAndStart(addr, cfg)
+		}
+		return listenAndStartTLS(addr, cert, key, cfg)
 	}
 
-	return listenAndStartTLS(cfg.Addr, cert, key)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.PortRange != [2]int{} {
+		return startInPortRange(start, host, cfg.PortRange)
+	}
+
+	if cfg.PreferredPort > 0 {
+		t, err := start(net.JoinHostPort(host, strconv.Itoa(cfg.PreferredPort)))
+		if err == nil {
+			return t, nil
+		}
+		Logger.Printf("techo: preferred port %v unavailable, using random port: %v\n", cfg.PreferredPort, err)
+	}
+
+	return start(addr)
+}
+
+// startInPortRange invokes start for each port in the range [min, max] of ports
+// in turn, until a server is successfully started.
+func startInPortRange(start func(addr string) (*Techo, error), host string, ports [2]int) (*Techo, error) {
+
+	min, max := ports[0], ports[1]
+	if min < 1 || max > 65535 || min > max {
+		return nil, fmt.Errorf("techo: invalid port range [%v, %v]", min, max)
+	}
+
+	var err error
+	for port := min; port <= max; port++ {
+		var t *Techo
+		t, err = start(net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("techo: unable to start server in port range [%v, %v]: %v", min, max, err)
 }
 
-func listenAndStart(addr string) (*Techo, error) {
+func listenAndStart(addr string, cfg *Config) (*Techo, error) {
+
+	t := newTecho(cfg)
+	err := t.listenAndServe(addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
 
-	t := new(Techo)
-	t.Echo = echo.New()
-	t.mutex = &sync.Mutex{}
+// listenAndServe listens on the TCP address addr, and starts serving.
+func (t *Techo) listenAndServe(addr string, cfg *Config) error {
 
 	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	t.setAddr(l.Addr(), "http")
+	err = t.serve(l, fmt.Sprintf(":%v", t.Port), cfg)
+	if err != nil {
+		l.Close()
+		return err
+	}
+	return nil
+}
+
+// setAddr sets t's address fields from addr, the address of the listener being
+// served. If addr is not a *net.TCPAddr (e.g. for a unix domain socket, or a
+// fake listener), Addr is nil, Port is zero, and URL is derived from addr's
+// string form ("http://unix" for a unix domain socket). The address is always
+// recorded, so that WaitForReady can dial it.
+func (t *Techo) setAddr(addr net.Addr, scheme string) {
+
+	t.listenAddr = addr
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		t.Addr = nil
+		t.Port = 0
+		host := addr.String()
+		if addr.Network() == "unix" {
+			host = "unix"
+		}
+		t.URL = scheme + "://" + host
+		return
+	}
+
+	t.Addr = tcpAddr
+	t.Port = tcpAddr.Port
+	t.URL = baseURL(scheme, tcpAddr)
+}
+
+// baseURL returns the base URL (scheme + host + port) for addr, e.g.
+// http://127.0.0.1:61241. An IPv6 host is bracketed, e.g. http://[::1]:61241.
+func baseURL(scheme string, addr *net.TCPAddr) string {
+	return fmt.Sprintf("%v://%v", scheme, net.JoinHostPort(addr.IP.String(), strconv.Itoa(addr.Port)))
+}
+
+// listenAndStartUnix starts a server listening on the unix domain socket at path.
+// As there is no host or port, URL is set to "http://unix": the client is expected
+// to dial SocketPath() itself.
+func listenAndStartUnix(path string, cfg *Config) (*Techo, error) {
+
+	if path == "" {
+		return nil, fmt.Errorf("techo: no socket path provided for unix network")
+	}
+
+	t := newTecho(cfg)
+	err := t.listenAndServeUnix(path, cfg)
 	if err != nil {
 		return nil, err
 	}
+	return t, nil
+}
+
+// listenAndServeUnix listens on the unix domain socket at path, and starts
+// serving.
+func (t *Techo) listenAndServeUnix(path string, cfg *Config) error {
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
 
-	t.Addr = l.Addr().(*net.TCPAddr)
-	t.Port = t.Addr.Port
-	t.URL = fmt.Sprintf("http://%v:%v", t.Addr.IP, t.Port)
-	std := standard.New(fmt.Sprintf(":%v", t.Addr.Port))
-	std.SetHandler(t.Echo)
-	t.srv = &graceful.Server{
-		Timeout: time.Millisecond * 1,
+	t.socketPath = path
+	t.setAddr(l.Addr(), "http")
+	err = t.serve(l, path, cfg)
+	if err != nil {
+		l.Close()
+		return err
+	}
+	return nil
+}
+
+// serve starts serving (on a new goroutine) connections accepted by l.
+func (t *Techo) serve(l net.Listener, addr string, cfg *Config) error {
+
+	std := standard.New(addr)
+	std.SetHandler(handler{t.instance})
+	err := configureServer(std.Server, cfg, false)
+	if err != nil {
+		return err
+	}
+	srv := &graceful.Server{
+		Timeout: t.shutdownTimeout,
 		Server:  std.Server,
 	}
 
+	t.started(srv, l, cfg)
+	return nil
+}
+
+// started sets srv as the server for t, registers t as live, and starts srv
+// serving (on a new goroutine) connections accepted by l. If temporary TLS files were written, a finalizer
+// is set on t that removes them if t is garbage-collected without having been
+// stopped. The finalizer does not stop the server, which may still be in use
+// via its URL.
+func (t *Techo) started(srv *graceful.Server, l net.Listener, cfg *Config) {
+
+	t.mutex.Lock()
+	t.srv = srv
+	t.cfg = cfg
+	t.stopped = false
+	if t.noKeepAlive {
+		t.srv.SetKeepAlivesEnabled(false)
+	}
+	tempTLSFiles := t.tempTLSFiles
+	ctx := t.ctx
+	t.mutex.Unlock()
+
+	// On Restart, the new server is tied to the context (if any) that the
+	// previous server was tied to, unless the context is already done.
+	if ctx != nil && ctx.Err() == nil {
+		t.watchContext(ctx)
+	}
+
+	register(t.instance)
+
+	// A finalizer may remain from before a Restart, if the server was stopped
+	// other than via t (e.g. by StopAll). It must be cleared before being set.
+	runtime.SetFinalizer(t, nil)
+	if tempTLSFiles && t.peer == nil {
+		runtime.SetFinalizer(t, func(t *Techo) {
+			t.instance.cleanupTLSFiles()
+		})
+	}
+
+	l = instanceListener{Listener: l, in: t.instance}
 	go func() {
-		err := t.srv.Serve(l)
+		err := srv.Serve(l)
 		if err != nil {
-			log.Printf("techo error: %v\n", err)
+			Logger.Printf("techo error: %v\n", err)
 		}
 	}()
+}
 
-	return t, nil
+// configureServer applies the settings in cfg to srv, prior to serving.
+func configureServer(srv *http.Server, cfg *Config, isTLS bool) error {
+
+	srv.ReadTimeout = cfg.ReadTimeout
+	srv.WriteTimeout = cfg.WriteTimeout
+	srv.IdleTimeout = cfg.IdleTimeout
+
+	if len(cfg.ClientCAs) > 0 || cfg.RequireClientCert {
+		if !isTLS {
+			return fmt.Errorf("techo: client certificates require a TLS server")
+		}
+		if len(cfg.ClientCAs) == 0 {
+			return fmt.Errorf("techo: RequireClientCert requires ClientCAs")
+		}
+
+		pool := x509.NewCertPool()
+		for _, ca := range cfg.ClientCAs {
+			if !pool.AppendCertsFromPEM(ca) {
+				return fmt.Errorf("techo: invalid client CA certificate")
+			}
+		}
+
+		if srv.TLSConfig == nil {
+			srv.TLSConfig = &tls.Config{}
+		}
+		srv.TLSConfig.ClientCAs = pool
+		srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.RequireClientCert {
+			srv.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	if cfg.HTTP2 {
+		h2s := &http2.Server{}
+		if !isTLS {
+			// Cleartext HTTP/2 (h2c)
+			srv.Handler = h2c.NewHandler(srv.Handler, h2s)
+		}
+		err := http2.ConfigureServer(srv, h2s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// instanceListener is a net.Listener that counts the connections it accepts,
+// delays returning each connection by the instance's accept delay, and wraps
+// each connection for raw capture if enabled.
+type instanceListener struct {
+	net.Listener
+	in *instance
+}
+
+func (l instanceListener) Accept() (net.Conn, error) {
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return conn, err
+	}
+
+	atomic.AddInt64(&l.in.accepted, 1)
+	if d := time.Duration(atomic.LoadInt64(&l.in.acceptDelay)); d > 0 {
+		time.Sleep(d)
+	}
+
+	l.in.mutex.Lock()
+	rc := l.in.rawCapture
+	l.in.mutex.Unlock()
+	if rc != nil {
+		conn = rc.wrap(conn)
+	}
+	return conn, nil
+}
+
+// countingWriter is an io.Writer that counts the bytes written to it.
+type countingWriter struct {
+	io.Writer
+	n *int64
+}
+
+func (w countingWriter) Write(b []byte) (int, error) {
+
+	n, err := w.Writer.Write(b)
+	atomic.AddInt64(w.n, int64(n))
+	return n, err
+}
+
+// handler is the engine.Handler bound to the server. It delegates to the
+// instance's current echo instance, which can be swapped out by Reset.
+type handler struct {
+	in *instance
+}
+
+func (h handler) ServeHTTP(req engine.Request, res engine.Response) {
+
+	atomic.AddInt64(&h.in.active, 1)
+	defer atomic.AddInt64(&h.in.active, -1)
+
+	h.in.mutex.Lock()
+	e := h.in.echo
+	h.in.counts[countKey(req.Method(), req.URL().Path())]++
+	h.in.mutex.Unlock()
+
+	res.SetWriter(countingWriter{Writer: res.Writer(), n: &h.in.written})
+	e.ServeHTTP(req, res)
 }
 
 // NewTLS starts a TLS/HTTPS server on a random port. In the unusual event of an error,
 // the error is logged, and nil is returned.
 func NewTLS() *Techo {
 
-	te, err := listenAndStartTLS("localhost:", defaultCert, defaultKey)
+	te, err := listenAndStartTLS("localhost:", defaultCert, defaultKey, &Config{})
 	if err != nil {
-		log.Println(err)
+		Logger.Println(err)
 		return nil
 	}
 	return te
 }
 
-func listenAndStartTLS(addr string, tlsCert []byte, tlsKey []byte) (*Techo, error) {
+// NewDual starts an HTTP server and a TLS/HTTPS server, each on a random port,
+// that share the same echo instance. Routes registered on either are served
+// over both schemes, which is useful for testing scheme upgrades, e.g. HTTP to
+// HTTPS redirects.
+//
+// Because the echo instance is shared, features that are installed as
+// middleware (e.g. SetLatency, EnableRecording, SetStrict, EnableGzip or
+// SetResponseHeader) apply to both servers, whichever server they are invoked
+// on, as does SetHTTPErrorHandler. Invoke each such feature on one server only (conventionally httpTe), as
+// invoking it on both installs it twice. Reset, invoked on either server,
+// resets both, so that they continue to share a (fresh) echo instance.
+//
+// Otherwise, the servers are independent: each has its own listener and
+// lifecycle (Stop, Restart, OnStop, StopChan), its own counters (RequestCount,
+// BytesWritten, ConnectionsAccepted, ActiveRequests), and its own
+// connection-level settings (SetAcceptDelay, DisableKeepAlive,
+// EnableRawCapture). Each server must be stopped individually; unlike NewTLS,
+// the temporary TLS files are not removed if tlsTe is garbage-collected
+// without being stopped.
+func NewDual() (httpTe *Techo, tlsTe *Techo, err error) {
+
+	httpTe, err = NewE()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsTe, err = listenAndStartTLS("localhost:", defaultCert, defaultKey, &Config{})
+	if err != nil {
+		httpTe.Stop()
+		return nil, nil, err
+	}
+
+	tlsTe.mutex.Lock()
+	tlsTe.Echo = httpTe.Echo
+	tlsTe.echo = httpTe.Echo
+	tlsTe.errorHandler = httpTe.errorHandler
+	tlsTe.mutex.Unlock()
+
+	// The peers reference each other, and a finalizer on an object in a cycle
+	// is never run, so there is no point in having one.
+	httpTe.peer, tlsTe.peer = tlsTe, httpTe
+	runtime.SetFinalizer(tlsTe, nil)
+	return httpTe, tlsTe, nil
+}
 
-	t := new(Techo)
-	t.Echo = echo.New()
-	t.mutex = &sync.Mutex{}
+func listenAndStartTLS(addr string, tlsCert []byte, tlsKey []byte, cfg *Config) (*Techo, error) {
 
-	err := t.writeTLSFiles(tlsCert, tlsKey)
+	t := newTecho(cfg)
+	err := t.listenAndServeTLS(addr, tlsCert, tlsKey, cfg)
 	if err != nil {
 		return nil, err
 	}
+	return t, nil
+}
 
-	std := standard.WithTLS(addr, t.certFilePath, t.keyFilePath)
-	std.SetHandler(t.Echo)
+// listenAndServeTLS listens on the TCP address addr, and starts serving TLS
+// using the supplied cert and key (or the files specified in cfg).
+func (t *Techo) listenAndServeTLS(addr string, tlsCert []byte, tlsKey []byte, cfg *Config) error {
 
-	t.srv = &graceful.Server{
-		Timeout: time.Millisecond * 1,
-		Server:  std.Server,
+	if cfg.TLSCertFile != "" {
+		// Serve from the user's files directly: the cert and key are still
+		// read into memory, for use by CertPEM, Client etc.
+		var err error
+		tlsCert, err = ioutil.ReadFile(cfg.TLSCertFile)
+		if err != nil {
+			return err
+		}
+		tlsKey, err = ioutil.ReadFile(cfg.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		t.certFilePath = cfg.TLSCertFile
+		t.keyFilePath = cfg.TLSKeyFile
 	}
 
-	l, err := t.srv.ListenTLS(t.certFilePath, t.keyFilePath)
+	var tlsConfig *tls.Config
+	if cfg.TLSConfig != nil {
+		// Serve using the config: no files are needed.
+		t.certFilePath, t.keyFilePath = "", ""
+		tlsConfig = cfg.TLSConfig.Clone()
+		if len(tlsConfig.Certificates) == 0 {
+			pair, err := tls.X509KeyPair(tlsCert, tlsKey)
+			if err != nil {
+				return err
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		} else {
+			// The served cert is the config's: expose it via CertPEM etc.
+			leaf := tlsConfig.Certificates[0].Certificate[0]
+			tlsCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf})
+			tlsKey = nil
+		}
+	} else if cfg.TLSCertFile == "" {
+		err := t.writeTLSFiles(cfg.TempDir, tlsCert, tlsKey)
+		if err != nil {
+			t.cleanupTLSFiles()
+			return err
+		}
+	}
+	t.cert = tlsCert
+	t.key = tlsKey
 
+	std := standard.WithTLS(addr, t.certFilePath, t.keyFilePath)
+	std.SetHandler(handler{t.instance})
+	std.Server.TLSConfig = tlsConfig
+	err := configureServer(std.Server, cfg, true)
 	if err != nil {
-		return nil, err
+		t.cleanupTLSFiles()
+		return err
 	}
 
-	t.Addr = l.Addr().(*net.TCPAddr)
-	t.Port = t.Addr.Port
-	t.URL = fmt.Sprintf("https://%v:%v", t.Addr.IP, t.Port)
+	srv := &graceful.Server{
+		Timeout: t.shutdownTimeout,
+		Server:  std.Server,
+	}
 
-	go func() {
-		err := t.srv.Serve(l)
-		if err != nil {
-			log.Printf("techo error: %v\n", err)
-		}
+	// The listener is created, and the address fields are set, before the
+	// server goroutine starts, so that an immediate Stop is safe. The TLS files
+	// are cleaned up by Stop, not by the server goroutine.
+	l, err := srv.ListenTLS(t.certFilePath, t.keyFilePath)
+	if err != nil {
 		t.cleanupTLSFiles()
-	}()
+		return err
+	}
 
-	return t, nil
+	t.setAddr(l.Addr(), "https")
+	t.TLS = true
+
+	t.started(srv, l, cfg)
+	return nil
 }
 
-// writeTLSFiles writes out the cert and key files required when using TLS. It is
-// necessary to write these to disk (as opposed to providing the bytes directly)
-// as the echo API requires these files be loaded from disk.
-func (t *Techo) writeTLSFiles(cert []byte, key []byte) error {
+// tlsFileMode is the file mode of the TLS cert and key files. In particular,
+// the key should not be readable by other users.
+const tlsFileMode os.FileMode = 0600
 
-	t.mutex.Lock()
-	defer t.mutex.Unlock()
-	certFile, err := ioutil.TempFile("", "techo-tls-cert_")
+// writeTLSFiles writes out the cert and key files required when using TLS, in
+// dir (or the OS temp dir if dir is empty). It is necessary to write these to
+// disk (as opposed to providing the bytes directly) as the echo API requires
+// these files be loaded from disk.
+func (in *instance) writeTLSFiles(dir string, cert []byte, key []byte) error {
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	in.tempTLSFiles = true
+	certFile, err := ioutil.TempFile(dir, "techo-tls-cert_")
 	if err != nil {
 		return err
 	}
+	certFile.Close()
+	in.certFilePath = certFile.Name()
 
-	err = ioutil.WriteFile(certFile.Name(), cert, os.ModePerm)
+	err = ioutil.WriteFile(in.certFilePath, cert, tlsFileMode)
 	if err != nil {
 		return err
 	}
 
-	keyFile, err := ioutil.TempFile("", "techo-tls-key_")
+	keyFile, err := ioutil.TempFile(dir, "techo-tls-key_")
 	if err != nil {
 		return err
 	}
+	keyFile.Close()
+	in.keyFilePath = keyFile.Name()
 
-	err = ioutil.WriteFile(keyFile.Name(), key, os.ModePerm)
+	err = ioutil.WriteFile(in.keyFilePath, key, tlsFileMode)
 	if err != nil {
 		return err
 	}
 
-	t.certFilePath = certFile.Name()
-	t.keyFilePath = keyFile.Name()
-
 	return nil
-
 }
 
 // cleanupTLSFiles attempts to delete the temporary TLS files created by tech.
-// Errors are logged but not returned.
-func (t *Techo) cleanupTLSFiles() {
+// Files supplied by the user are left in place. Errors are logged but not
+// returned.
+func (in *instance) cleanupTLSFiles() {
 
-	t.mutex.Lock()
-	defer t.mutex.Unlock()
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	if !in.tempTLSFiles {
+		return
+	}
 
-	if t.certFilePath != "" {
-		err := os.Remove(t.certFilePath)
+	if in.certFilePath != "" {
+		err := os.Remove(in.certFilePath)
 		if err != nil {
-			log.Println(err)
+			Logger.Println(err)
 		}
-		t.certFilePath = ""
+		in.certFilePath = ""
 	}
-	if t.keyFilePath != "" {
-		err := os.Remove(t.keyFilePath)
+	if in.keyFilePath != "" {
+		err := os.Remove(in.keyFilePath)
 		if err != nil {
-			log.Println(err)
+			Logger.Println(err)
 		}
-		t.keyFilePath = ""
+		in.keyFilePath = ""
 	}
 
 }
 
-// Stop instructs the server to shut down.
+// Stop instructs the server to shut down, and waits for it to do so. In-flight
+// requests are given the configured shutdown timeout (1ms by default) to
+// complete. It is safe to call Stop multiple times.
 func (t *Techo) Stop() {
-	t.srv.Stop(time.Millisecond * 1)
-	t.cleanupTLSFiles()
+	t.StopWithTimeout(t.shutdownTimeout)
+}
+
+// StopWithTimeout is like Stop, but in-flight requests are given the supplied
+// timeout to complete. If timeout is zero, in-flight requests are waited on
+// indefinitely.
+func (t *Techo) StopWithTimeout(timeout time.Duration) {
+	runtime.SetFinalizer(t, nil)
+	t.instance.stop(timeout)
+}
+
+// stop shuts down the server, cleans up, and invokes the OnStop callbacks.
+// It does nothing if the instance is already stopped.
+func (in *instance) stop(timeout time.Duration) {
+
+	in.mutex.Lock()
+	if in.stopped {
+		in.mutex.Unlock()
+		return
+	}
+	in.stopped = true
+	srv := in.srv
+	in.mutex.Unlock()
+
+	srv.Stop(timeout)
+	<-srv.StopChan()
+	in.cleanupTLSFiles()
+	in.cleanupSocket()
+	unregister(in)
+
+	in.mutex.Lock()
+	fns := in.onStop
+	in.mutex.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Restart starts serving again on the same address (or unix socket) after the
+// server has been stopped (whether by Stop, StopAll, or a context passed to
+// NewWithContext), with the existing routes and middleware. An error is
+// returned if the server is not stopped, or if the address is no longer
+// available, e.g. because another process has since grabbed the port.
+func (t *Techo) Restart() error {
+
+	t.mutex.Lock()
+	stopped, cfg := t.stopped, t.cfg
+	t.mutex.Unlock()
+
+	if !stopped {
+		return fmt.Errorf("techo: cannot restart server at %v: not stopped", t.URL)
+	}
+
+	var err error
+	switch {
+	case t.socketPath != "":
+		err = t.listenAndServeUnix(t.socketPath, cfg)
+	case t.Addr == nil:
+		return fmt.Errorf("techo: cannot restart server at %v: not a TCP address", t.URL)
+	case t.TLS:
+		err = t.listenAndServeTLS(t.Addr.String(), t.cert, t.key, cfg)
+	default:
+		err = t.listenAndServe(t.Addr.String(), cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("techo: unable to restart server at %v: %v", t.URL, err)
+	}
+	return nil
+}
+
+// Close stops the server (see Stop), and returns nil. It allows Techo to be used
+// as an io.Closer.
+func (t *Techo) Close() error {
+	t.Stop()
+	return nil
+}
+
+// StopChan returns a channel that is closed when the server has shut down. This
+// allows tests to select on shutdown, rather than polling. The channel belongs
+// to the current server: after Restart, the returned channel remains closed,
+// so StopChan must be called again to wait on the restarted server.
+func (t *Techo) StopChan() <-chan struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.srv.StopChan()
+}
+
+// StopAndDrain is like StopWithTimeout, but returns an error if any in-flight
+// requests did not complete within timeout, and were thus forcibly terminated.
+func (t *Techo) StopAndDrain(timeout time.Duration) error {
+
+	runtime.SetFinalizer(t, nil)
+	return t.instance.stopAndDrain(timeout)
+}
+
+// stopAndDrain stops the instance, returning an error if any in-flight requests
+// did not complete within timeout.
+func (in *instance) stopAndDrain(timeout time.Duration) error {
+
+	in.stop(timeout)
+	n := atomic.LoadInt64(&in.active)
+	if n > 0 {
+		return fmt.Errorf("techo: %v in-flight request(s) terminated after %v", n, timeout)
+	}
+	return nil
+}
+
+// RequestCount returns the number of requests received with the supplied
+// method and request path, e.g. RequestCount(echo.GET, "/users/1"). This is
+// useful for verifying that a client did not make redundant calls. Requests
+// are counted whether or not a route matched.
+func (t *Techo) RequestCount(method, path string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.counts[countKey(method, path)]
+}
+
+// BytesWritten returns the total number of response body bytes written by the
+// server, as sent on the wire (e.g. after gzip compression). Bytes written
+// directly to the underlying http.ResponseWriter, bypassing echo's response,
+// are not counted.
+func (t *Techo) BytesWritten() int64 {
+	return atomic.LoadInt64(&t.written)
+}
+
+// countKey returns the key in instance.counts for method and path.
+func countKey(method, path string) string {
+	return method + " " + path
+}
+
+// ConnectionsAccepted returns the total number of connections accepted by the
+// server since it started. This is useful for verifying whether a client
+// reuses connections.
+func (t *Techo) ConnectionsAccepted() int {
+	return int(atomic.LoadInt64(&t.accepted))
+}
+
+// SetAcceptDelay causes the server to wait for d after accepting each
+// connection, before serving it, simulating a server with a connection backlog.
+// Note that the TCP handshake is completed by the OS regardless, so a client
+// dial will succeed: it is the client's subsequent timeouts (e.g. TLS handshake,
+// response header, or overall request timeouts) that are exercised. Because
+// connections are accepted one at a time, the delays are cumulative for
+// concurrent connections. A zero d removes the delay.
+func (t *Techo) SetAcceptDelay(d time.Duration) {
+	atomic.StoreInt64(&t.acceptDelay, int64(d))
+}
+
+// DisableKeepAlive disables HTTP keep-alives on the server: each response
+// carries a "Connection: close" header, and the connection is closed after the
+// response is sent, forcing clients to reconnect for each request. The setting
+// survives Restart.
+func (t *Techo) DisableKeepAlive() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.noKeepAlive = true
+	t.srv.SetKeepAlivesEnabled(false)
+}
+
+// ActiveRequests returns the number of requests currently being handled by the
+// server. This is useful for verifying the concurrency behavior of a client.
+func (t *Techo) ActiveRequests() int {
+	return int(atomic.LoadInt64(&t.active))
+}
+
+// OnStop registers fn to be invoked when the server is stopped. Callbacks are
+// invoked synchronously by Stop, after the server has shut down and temporary
+// files have been cleaned up, in the order they were registered.
+func (t *Techo) OnStop(fn func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.onStop = append(t.onStop, fn)
+}
+
+// cleanupSocket removes the unix domain socket file, if any.
+// Errors are logged but not returned.
+func (in *instance) cleanupSocket() {
+
+	if in.socketPath == "" {
+		return
+	}
+
+	err := os.Remove(in.socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		Logger.Println(err)
+	}
+}
+
+// SocketPath returns the path of the unix domain socket the server is listening
+// on, or empty string if the server is not listening on a unix socket.
+func (t *Techo) SocketPath() string {
+	return t.socketPath
+}
+
+// Reset replaces the embedded echo instance with a fresh one, without restarting
+// the server. All routes and middleware are discarded (except for the route at
+// Config.HealthPath, which is re-registered), as is any techo state such as
+// recorded requests. This is useful for reusing an instance across
+// subtests. For servers started by NewDual, both servers are reset.
+func (t *Techo) Reset() {
+
+	e, eh := t.reset(nil, nil)
+	if t.peer != nil {
+		t.peer.reset(e, eh)
+	}
+}
+
+// reset implements Reset, replacing the echo instance with e (whose error
+// handler is eh), or with a fresh echo instance if e is nil. It returns the
+// echo instance and error handler now in use.
+func (t *Techo) reset(e *echo.Echo, eh *errorHandler) (*echo.Echo, *errorHandler) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if e == nil {
+		e, eh = t.newEcho()
+	}
+	t.Echo = e
+	t.echo = e
+	t.errorHandler = eh
+	t.recorder = nil
+	t.latency = nil
+	t.pathLatency = nil
+	t.jitter = nil
+	t.faults = nil
+	t.panics = nil
+	t.basicAuth = nil
+	t.bearerAuth = nil
+	t.gzipEnabled = false
+	t.drainBody = false
+	t.autoOptions = false
+	t.bodyLimit = nil
+	t.responseHeaders = nil
+	t.cors = nil
+	t.notFound = nil
+	t.matching = nil
+	t.interceptor = nil
+	t.strict = nil
+	t.counts = map[string]int{}
+	atomic.StoreInt64(&t.written, 0)
+	atomic.StoreInt64(&t.acceptDelay, 0)
+	t.rawCapture = nil
+	if t.noKeepAlive {
+		t.noKeepAlive = false
+		t.srv.SetKeepAlivesEnabled(true)
+	}
+	return e, eh
+}
+
+// WaitForReady blocks until the server accepts connections, or until timeout
+// elapses, in which case an error is returned.
+func (t *Techo) WaitForReady(timeout time.Duration) error {
+
+	var network, addr string
+	switch {
+	case t.socketPath != "":
+		network, addr = "unix", t.socketPath
+	case t.Addr != nil:
+		network, addr = "tcp", t.Addr.String()
+	case t.listenAddr != nil:
+		network, addr = t.listenAddr.Network(), t.listenAddr.String()
+	default:
+		return fmt.Errorf("techo: server has no address")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout(network, addr, timeout)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("techo: server at %v not ready after %v: %v", addr, timeout, err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
 }
 
 func (t *Techo) String() string {
@@ -279,6 +1272,152 @@ func (t *Techo) AbsURL(path string) string {
 	return t.URL + "/" + path
 }
 
+// HostPort returns the server's dialable "host:port" address, e.g.
+// "127.0.0.1:61241", or "[::1]:61241" for IPv6. This is useful for clients
+// that take a raw address rather than a URL. It returns empty string for a
+// server listening on a unix domain socket.
+func (t *Techo) HostPort() string {
+
+	if t.Addr == nil {
+		return ""
+	}
+	return net.JoinHostPort(t.Addr.IP.String(), strconv.Itoa(t.Port))
+}
+
+// BaseURL returns the server's base URL (see the URL field) as a freshly parsed
+// *url.URL, which the caller is free to modify.
+func (t *Techo) BaseURL() *url.URL {
+
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		// Shouldn't happen, as techo constructs the URL itself
+		Logger.Println(err)
+		return nil
+	}
+	return u
+}
+
+// AbsURLf is like AbsURL, but constructs the path from a format string and args,
+// as per fmt.Sprintf. For example, te.AbsURLf("/users/%d", 42).
+func (t *Techo) AbsURLf(format string, args ...interface{}) string {
+	return t.AbsURL(fmt.Sprintf(format, args...))
+}
+
+// SetHTTPErrorHandler sets the handler that converts errors returned by route
+// handlers (e.g. echo.NewHTTPError(http.StatusTeapot)) into responses. It can
+// be called at any time, including after the server has started serving. A nil
+// h, like Reset, restores echo's default error handler.
+func (t *Techo) SetHTTPErrorHandler(h echo.HTTPErrorHandler) {
+
+	t.mutex.Lock()
+	eh := t.errorHandler
+	t.mutex.Unlock()
+
+	eh.mutex.Lock()
+	defer eh.mutex.Unlock()
+	eh.h = h
+}
+
+// AbsURLWithQuery is like AbsURL, but also appends the URL-encoded query. If
+// path already has a query string, the query is appended to it. For example,
+// te.AbsURLWithQuery("/search", url.Values{"q": {"fish & chips"}}) could return
+// "http://127.0.0.1:53262/search?q=fish+%26+chips".
+func (t *Techo) AbsURLWithQuery(path string, query url.Values) string {
+
+	u := t.AbsURL(path)
+	if len(query) == 0 {
+		return u
+	}
+
+	if strings.Contains(path, "?") {
+		return u + "&" + query.Encode()
+	}
+	return u + "?" + query.Encode()
+}
+
+// Static serves the files under the root dir for requests under prefix. For
+// example, te.Static("/fixtures", "testdata") serves file testdata/a.json at
+// path /fixtures/a.json. In the event of an error (e.g. root does not exist),
+// the error is logged. Use StaticE to handle the error yourself.
+func (t *Techo) Static(prefix, root string) {
+	err := t.StaticE(prefix, root)
+	if err != nil {
+		Logger.Println(err)
+	}
+}
+
+// StaticE is like Static, but returns an error if root is not an existing dir.
+func (t *Techo) StaticE(prefix, root string) error {
+
+	fi, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("techo: static root is not a dir: %v", root)
+	}
+
+	t.Echo.Static(prefix, root)
+	return nil
+}
+
+// StaticFS serves the files in fsys for requests under prefix. For example,
+// te.StaticFS("/fixtures", http.FS(fsys)) serves file a.json in fsys at path
+// /fixtures/a.json. This is useful for serving in-memory fixtures, e.g. from an
+// fstest.MapFS, without touching disk.
+func (t *Techo) StaticFS(prefix string, fsys http.FileSystem) {
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	h := http.StripPrefix(prefix, http.FileServer(fsys))
+	t.GET(prefix+"/*", standard.WrapHandler(h))
+}
+
+// Handle registers h as the handler for method and path. This allows mounting
+// existing http.Handler code without converting it to an echo handler.
+func (t *Techo) Handle(method, path string, h http.Handler) {
+	t.Match([]string{method}, path, standard.WrapHandler(h))
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	// Method is the HTTP method, e.g. "GET".
+	Method string
+	// Path is the route path, e.g. "/users/:id".
+	Path string
+}
+
+// String returns the route in the form "GET /users/:id".
+func (r RouteInfo) String() string {
+	return r.Method + " " + r.Path
+}
+
+// Routes returns every route registered on the server, in the order they
+// were registered, including those registered by helpers such as Respond or
+// Static. This is useful for debugging test setup. Use Echo.Routes for the
+// full echo route details.
+func (t *Techo) Routes() []RouteInfo {
+
+	routes := t.Echo.Routes()
+	infos := make([]RouteInfo, len(routes))
+	for i, r := range routes {
+		infos[i] = RouteInfo{Method: r.Method, Path: r.Path}
+	}
+	return infos
+}
+
+// stdRequest returns the *http.Request underlying c. This is safe because techo
+// always uses the standard engine.
+func stdRequest(c echo.Context) *http.Request {
+	return c.Request().(*standard.Request).Request
+}
+
+// stdResponseWriter returns the http.ResponseWriter underlying c. This is safe
+// because techo always uses the standard engine.
+func stdResponseWriter(c echo.Context) http.ResponseWriter {
+	return c.Response().(*standard.Response).ResponseWriter
+}
+
 var defaultCert []byte
 var defaultKey []byte
 
@@ -304,6 +1443,54 @@ func SetDefaultTLSCert(cert []byte, key []byte) {
 
 }
 
+// CertPEM returns the PEM-encoded TLS cert being served, or nil for a non-TLS server.
+func (t *Techo) CertPEM() []byte {
+	return t.cert
+}
+
+// KeyPEM returns the PEM-encoded TLS private key being used, or nil for a non-TLS server.
+// It is also nil if the certificate was supplied via Config.TLSConfig.
+func (t *Techo) KeyPEM() []byte {
+	return t.key
+}
+
+// CertFilePath returns the path of the TLS cert file being served, e.g. for
+// pointing an external tool at it. This is a temp file written by techo (unless
+// Config.TLSCertFile was supplied). It returns empty string for a non-TLS server,
+// if the cert was supplied via Config.TLSConfig, or after the temp file has been
+// cleaned up by Stop.
+func (t *Techo) CertFilePath() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.certFilePath
+}
+
+// KeyFilePath is like CertFilePath, but returns the path of the TLS private
+// key file.
+func (t *Techo) KeyFilePath() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.keyFilePath
+}
+
+// Client returns a new http.Client for making requests to the server. For a TLS
+// server, the client's root CAs contain exactly the cert being served, so that
+// verification succeeds without resorting to InsecureSkipVerify. For a non-TLS
+// server, a plain client is returned.
+func (t *Techo) Client() *http.Client {
+
+	if len(t.cert) == 0 {
+		return &http.Client{}
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(t.cert)
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	return &http.Client{Transport: tr}
+}
+
 // SkipDefaultClientInsecureTLSVerify is a convenience method that sets
 // InsecureSkipVerify to true on http.DefaultClient. This means that you can use
 // insecure certs without receiving an error (assuming your client is using
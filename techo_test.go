@@ -1,14 +1,38 @@
 package techo
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/labstack/echo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
 )
 
 func TestNew(t *testing.T) {
@@ -30,6 +54,192 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, "hello world", string(body))
 }
 
+func TestNewE(t *testing.T) {
+
+	te, err := NewE()
+	require.Nil(t, err)
+	require.NotNil(t, te)
+	defer te.Stop()
+
+	// The port is already bound by te, so this must fail.
+	te2, err := listenAndStart(fmt.Sprintf("localhost:%v", te.Port), &Config{})
+	require.NotNil(t, err)
+	require.Nil(t, te2)
+}
+
+func TestNewT(t *testing.T) {
+
+	var _ TB = testing.TB(nil)
+
+	te := NewT(t)
+	require.NotNil(t, te)
+	require.Nil(t, te.WaitForReady(time.Second))
+
+	tb := &fakeTB{TB: t}
+	te = NewT(tb)
+	require.NotNil(t, te)
+	require.Equal(t, 1, len(tb.cleanups))
+	assert.Empty(t, tb.fatals)
+
+	// Invoking the cleanup stops the server.
+	tb.cleanups[0]()
+	l, err := net.Listen("tcp", te.Addr.String())
+	require.Nil(t, err, "port should be free after cleanup")
+	l.Close()
+
+	tb = &fakeTB{TB: t}
+	te = newT(tb, func() (*Techo, error) {
+		return nil, fmt.Errorf("failed")
+	})
+	assert.Nil(t, te)
+	require.Equal(t, 1, len(tb.fatals))
+	assert.Contains(t, tb.fatals[0], "failed")
+	assert.Empty(t, tb.cleanups)
+}
+
+func TestNewWithContext(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	te := NewWithContext(ctx)
+	require.NotNil(t, te)
+	defer te.Stop()
+	require.Nil(t, te.WaitForReady(time.Second))
+
+	// Once stopped, the port is unbound.
+	waitUnbound := func() {
+		deadline := time.Now().Add(time.Second * 5)
+		for {
+			l, err := net.Listen("tcp", te.Addr.String())
+			if err == nil {
+				l.Close()
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("port %v still bound after context cancelled", te.Port)
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+
+	// Restarted before the context is done, the server is still tied to it.
+	te.Stop()
+	require.Nil(t, te.Restart())
+	require.Nil(t, te.WaitForReady(time.Second))
+
+	cancel()
+	waitUnbound()
+
+	// Restarted after the context is done, the server keeps running.
+	require.Nil(t, te.Restart())
+	time.Sleep(time.Millisecond * 50)
+	status, _, err := te.Get("/")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestWaitForReady(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	require.Nil(t, te.WaitForReady(time.Second))
+
+	// Grab a free port, and then release it: nothing is listening there.
+	l, err := net.Listen("tcp", "localhost:")
+	require.Nil(t, err)
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+
+	dead := newTecho(&Config{})
+	dead.Addr = addr
+	err = dead.WaitForReady(time.Millisecond * 50)
+	require.NotNil(t, err)
+}
+
+func TestNewWithListener(t *testing.T) {
+
+	l, err := net.Listen("tcp", "localhost:")
+	require.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	te, err := NewWithListener(l)
+	require.Nil(t, err)
+	defer te.Stop()
+	assert.Equal(t, port, te.Port)
+	assert.Equal(t, "http://"+l.Addr().String(), te.URL)
+
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	status, body, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	// Stopping the server closes the listener.
+	te.Stop()
+	_, err = l.Accept()
+	require.NotNil(t, err)
+}
+
+// fakeAddrListener is a net.Listener that reports a non-TCP address.
+type fakeAddrListener struct {
+	net.Listener
+}
+
+type fakeAddr struct{}
+
+func (fakeAddr) Network() string { return "fake" }
+func (fakeAddr) String() string  { return "fake-addr" }
+
+func (l fakeAddrListener) Addr() net.Addr {
+	return fakeAddr{}
+}
+
+func TestNewWithListenerNonTCP(t *testing.T) {
+
+	inner, err := net.Listen("tcp", "localhost:")
+	require.Nil(t, err)
+
+	te, err := NewWithListener(fakeAddrListener{Listener: inner})
+	require.Nil(t, err)
+	defer te.Stop()
+
+	assert.Nil(t, te.Addr)
+	assert.Equal(t, 0, te.Port)
+	assert.Equal(t, "http://fake-addr", te.URL)
+	assert.Equal(t, "", te.HostPort())
+	require.NotNil(t, te.WaitForReady(time.Millisecond*50), "fake address is not dialable")
+
+	// The server still serves connections accepted by the listener.
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	resp, err := http.Get("http://" + inner.Addr().String() + "/hello")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	te.Stop()
+	require.NotNil(t, te.Restart())
+}
+
+func TestNewWithEcho(t *testing.T) {
+
+	e := echo.New()
+	e.GET("/app", func(c echo.Context) error {
+		return c.String(http.StatusOK, "my app")
+	})
+
+	te, err := NewWithEcho(e)
+	require.Nil(t, err)
+	defer te.Stop()
+	assert.True(t, te.Echo == e)
+
+	resp, err := http.Get(te.AbsURL("/app"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "my app", string(body))
+}
+
 func TestNewWith(t *testing.T) {
 
 	// So, we want to test that we can start a server (using NewAt) at a specific address,
@@ -97,27 +307,270 @@ func TestNewWith(t *testing.T) {
 
 }
 
-func TestNewTLS(t *testing.T) {
+func TestNewWithPortRange(t *testing.T) {
 
-	te := NewTLS()
+	// Find a pair of consecutive ports, the first of which we hold onto,
+	// and the second of which is free.
+	var reserved net.Listener
+	var port int
+	for i := 0; i < 100 && reserved == nil; i++ {
+		l, err := net.Listen("tcp", "localhost:")
+		require.Nil(t, err)
+		port = l.Addr().(*net.TCPAddr).Port
+
+		l2, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port+1))
+		if err != nil {
+			l.Close()
+			continue
+		}
+		l2.Close()
+		reserved = l
+	}
+	require.NotNil(t, reserved)
+	defer reserved.Close()
+
+	te, err := NewWith(&Config{PortRange: [2]int{port, port + 1}})
+	require.Nil(t, err)
+	defer te.Stop()
+	assert.Equal(t, port+1, te.Port)
+
+	// No free port in range
+	_, err = NewWith(&Config{PortRange: [2]int{port, port}})
+	require.NotNil(t, err)
+
+	// Invalid range
+	_, err = NewWith(&Config{PortRange: [2]int{port + 1, port}})
+	require.NotNil(t, err)
+}
+
+func TestNewWithPreferredPort(t *testing.T) {
+
+	// Grab a free port, and then release it.
+	l, err := net.Listen("tcp", "localhost:")
+	require.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	te, err := NewWith(&Config{PreferredPort: port})
+	require.Nil(t, err)
+	defer te.Stop()
+	assert.Equal(t, port, te.Port)
+
+	orig := Logger
+	defer func() { Logger = orig }()
+	buf := &syncBuffer{}
+	Logger = log.New(buf, "", 0)
+
+	// The preferred port is now taken by te, so te2 falls back.
+	te2, err := NewWith(&Config{PreferredPort: port})
+	require.Nil(t, err)
+	defer te2.Stop()
+	assert.NotEqual(t, port, te2.Port)
+	assert.NotEqual(t, 0, te2.Port)
+	assert.Contains(t, buf.String(), "preferred port")
+}
+
+func TestNewWithIPv6(t *testing.T) {
+
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	l.Close()
+
+	te, err := NewWith(&Config{IPv6: true})
+	require.Nil(t, err)
 	defer te.Stop()
+	assert.Equal(t, fmt.Sprintf("http://[::1]:%v", te.Port), te.URL)
+
 	te.GET("/hello", func(c echo.Context) error {
 		return c.String(http.StatusOK, "hello world")
 	})
 
-	SkipDefaultClientInsecureTLSVerify()
 	resp, err := http.Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
 	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestNewWithUnixSocket(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "techo-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sockPath := filepath.Join(dir, "techo.sock")
+	te, err := NewWith(&Config{Network: "unix", SocketPath: sockPath})
+	require.Nil(t, err)
+	require.NotNil(t, te)
+	require.Equal(t, sockPath, te.SocketPath())
+
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", te.SocketPath())
+			},
+		},
+	}
+
+	resp, err := client.Get(te.AbsURL("/hello"))
 	require.Nil(t, err)
+	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	require.Nil(t, err)
 	assert.Equal(t, "hello world", string(body))
+
+	te.Stop()
+	_, err = os.Stat(sockPath)
+	assert.True(t, os.IsNotExist(err))
 }
 
-func TestTLSWithUserCerts(t *testing.T) {
+func TestShutdownTimeout(t *testing.T) {
 
-	SetDefaultTLSCert(testCert, testKey)
+	te, err := NewWith(&Config{ShutdownTimeout: time.Second})
+	require.Nil(t, err)
+	assert.Equal(t, time.Second, te.srv.Timeout, "should be passed through to graceful")
+
+	started := make(chan struct{})
+	completed := make(chan struct{})
+	te.GET("/slow", func(c echo.Context) error {
+		close(started)
+		time.Sleep(time.Millisecond * 100)
+		close(completed)
+		return c.String(http.StatusOK, "done")
+	})
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(te.AbsURL("/slow"))
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	<-started
+	te.Stop()
+	select {
+	case <-completed:
+	default:
+		t.Error("handler should have completed")
+	}
+	assert.Equal(t, http.StatusOK, <-status)
+}
+
+func TestStopWithTimeout(t *testing.T) {
+
+	te := New()
+
+	started := make(chan struct{})
+	completed := make(chan struct{})
+	te.GET("/slow", func(c echo.Context) error {
+		close(started)
+		time.Sleep(time.Millisecond * 100)
+		close(completed)
+		return c.String(http.StatusOK, "done")
+	})
+
+	go func() {
+		resp, err := http.Get(te.AbsURL("/slow"))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	te.StopWithTimeout(time.Second)
+	select {
+	case <-completed:
+	default:
+		t.Error("handler should have completed")
+	}
+
+	// Stopping again should be harmless
+	te.Stop()
+}
+
+func TestReset(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.EnableRecording()
+	te.GET("/a", func(c echo.Context) error {
+		return c.String(http.StatusOK, "first")
+	})
+
+	resp, err := http.Get(te.AbsURL("/a"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, 1, len(te.Requests()))
+
+	te.Reset()
+	assert.Nil(t, te.Requests())
+	te.GET("/a", func(c echo.Context) error {
+		return c.String(http.StatusOK, "second")
+	})
+
+	resp, err = http.Get(te.AbsURL("/a"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "second", string(body))
+}
+
+func TestRestart(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+	port := te.Port
+
+	require.NotNil(t, te.Restart(), "server is not stopped")
+
+	te.Stop()
+	_, _, err := te.Get("/hello")
+	require.NotNil(t, err)
+
+	require.Nil(t, te.Restart())
+	assert.Equal(t, port, te.Port)
+	status, body, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	// Restart also works after the server was stopped by StopAll.
+	StopAll()
+	_, _, err = te.Get("/hello")
+	require.NotNil(t, err)
+	require.Nil(t, te.Restart())
+	status, body, err = te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	// If the port is grabbed while stopped, restart fails.
+	te.Stop()
+	l, err := net.Listen("tcp", te.Addr.String())
+	require.Nil(t, err)
+	defer l.Close()
+	require.NotNil(t, te.Restart())
+}
+
+func TestRestartTLS(t *testing.T) {
 
 	te := NewTLS()
 	defer te.Stop()
@@ -125,16 +578,1239 @@ func TestTLSWithUserCerts(t *testing.T) {
 		return c.String(http.StatusOK, "hello world")
 	})
 
-	SkipDefaultClientInsecureTLSVerify()
-	resp, err := http.Get(te.AbsURL("/hello"))
-	defer resp.Body.Close()
+	te.Stop()
+	require.Nil(t, te.Restart())
+	status, body, err := te.Get("/hello")
 	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// When stopped by StopAll, the finalizer set on the first start is not
+	// cleared, so restarting must not attempt to set a second one.
+	StopAll()
+	require.Nil(t, te.Restart())
+	status, body, err = te.Get("/hello")
 	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, "hello world", string(body))
 }
 
+func TestStopChan(t *testing.T) {
+
+	te := New()
+	stopCh := te.StopChan()
+
+	done := make(chan struct{})
+	go func() {
+		<-stopCh
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("goroutine unblocked before Stop")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	te.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("goroutine did not unblock after Stop")
+	}
+
+	// StopChan may be called concurrently with Restart.
+	go func() {
+		for i := 0; i < 100; i++ {
+			te.StopChan()
+		}
+	}()
+	require.Nil(t, te.Restart())
+	defer te.Stop()
+
+	// The old channel remains closed; a new one must be obtained.
+	select {
+	case <-stopCh:
+	default:
+		t.Fatal("old channel should remain closed after Restart")
+	}
+	select {
+	case <-te.StopChan():
+		t.Fatal("new channel should not be closed before Stop")
+	default:
+	}
+}
+
+func TestClose(t *testing.T) {
+
+	var _ io.Closer = (*Techo)(nil)
+
+	te := New()
+	require.NotNil(t, te)
+	require.Nil(t, te.Close())
+
+	l, err := net.Listen("tcp", te.Addr.String())
+	require.Nil(t, err, "port should be free after Close")
+	l.Close()
+
+	require.Nil(t, te.Close(), "Close is idempotent")
+}
+
+func TestStopAndDrain(t *testing.T) {
+
+	te := New()
+
+	started := make(chan struct{})
+	done := make(chan int, 1)
+	te.GET("/slow", func(c echo.Context) error {
+		close(started)
+		time.Sleep(time.Millisecond * 100)
+		return c.String(http.StatusOK, "done")
+	})
+
+	go func() {
+		resp, err := http.Get(te.AbsURL("/slow"))
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+
+	<-started
+	err := te.StopAndDrain(time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	// Now with a timeout too short for the request to complete
+	te2 := New()
+	started2 := make(chan struct{})
+	te2.GET("/slow", func(c echo.Context) error {
+		close(started2)
+		time.Sleep(time.Millisecond * 500)
+		return c.String(http.StatusOK, "done")
+	})
+
+	go func() {
+		resp, err := http.Get(te2.AbsURL("/slow"))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started2
+	err = te2.StopAndDrain(time.Millisecond * 10)
+	require.NotNil(t, err)
+}
+
+func TestRequestCount(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/a", http.StatusOK, "a")
+	te.Respond(echo.GET, "/b", http.StatusOK, "b")
+
+	for _, path := range []string{"/a", "/a?x=1", "/b", "/nope"} {
+		_, _, err := te.Get(path)
+		require.Nil(t, err)
+	}
+
+	assert.Equal(t, 2, te.RequestCount(echo.GET, "/a"))
+	assert.Equal(t, 1, te.RequestCount(echo.GET, "/b"))
+	assert.Equal(t, 1, te.RequestCount(echo.GET, "/nope"))
+	assert.Equal(t, 0, te.RequestCount(echo.POST, "/a"))
+
+	te.Reset()
+	assert.Equal(t, 0, te.RequestCount(echo.GET, "/a"))
+}
+
+func TestBytesWritten(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	body := strings.Repeat("x", 1000)
+	te.Respond(echo.GET, "/body", http.StatusOK, body)
+	te.Respond(echo.GET, "/empty", http.StatusNoContent, "")
+
+	assert.Equal(t, int64(0), te.BytesWritten())
+
+	_, got, err := te.Get("/body")
+	require.Nil(t, err)
+	require.Equal(t, body, string(got))
+	assert.Equal(t, int64(1000), te.BytesWritten())
+
+	_, _, err = te.Get("/empty")
+	require.Nil(t, err)
+	_, _, err = te.Get("/body")
+	require.Nil(t, err)
+	assert.Equal(t, int64(2000), te.BytesWritten())
+
+	te.Reset()
+	assert.Equal(t, int64(0), te.BytesWritten())
+}
+
+func TestConnectionsAccepted(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	get := func(client *http.Client) {
+		resp, err := client.Get(te.AbsURL("/hello"))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		_, err = ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+	}
+
+	keepAlive := &http.Client{Transport: &http.Transport{}}
+	get(keepAlive)
+	get(keepAlive)
+	assert.Equal(t, 1, te.ConnectionsAccepted())
+
+	noKeepAlive := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get(noKeepAlive)
+	get(noKeepAlive)
+	assert.Equal(t, 3, te.ConnectionsAccepted())
+}
+
+func TestDisableKeepAlive(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+	te.DisableKeepAlive()
+
+	// The client would reuse connections, if the server allowed it.
+	client := &http.Client{Transport: &http.Transport{}}
+	for i := 1; i <= 3; i++ {
+		resp, err := client.Get(te.AbsURL("/hello"))
+		require.Nil(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.True(t, resp.Close, "response should carry Connection: close")
+		assert.Equal(t, i, te.ConnectionsAccepted())
+	}
+
+	// The client strips the Connection header, so check the raw response.
+	conn, err := net.Dial("tcp", te.Addr.String())
+	require.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.Nil(t, err)
+	raw, err := ioutil.ReadAll(conn) // The server closes the connection
+	require.Nil(t, err)
+	assert.Contains(t, string(raw), "Connection: close\r\n")
+}
+
+func TestSetAcceptDelay(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	delay := time.Millisecond * 300
+	te.SetAcceptDelay(delay)
+
+	// Don't reuse connections, so that each request is a new connection.
+	newClient := func(timeout time.Duration) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{DisableKeepAlives: true},
+			Timeout:   timeout,
+		}
+	}
+
+	_, err := newClient(time.Millisecond * 50).Get(te.AbsURL("/hello"))
+	require.NotNil(t, err, "expected timeout")
+
+	// Let the server work through the backlog.
+	time.Sleep(delay)
+
+	start := time.Now()
+	resp, err := newClient(time.Second * 5).Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, time.Since(start) >= delay)
+
+	te.SetAcceptDelay(0)
+	start = time.Now()
+	resp, err = newClient(time.Second * 5).Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.True(t, time.Since(start) < delay)
+}
+
+func TestActiveRequests(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	const n = 5
+	arrived := make(chan struct{}, n)
+	release := make(chan struct{})
+	te.GET("/slow", func(c echo.Context) error {
+		arrived <- struct{}{}
+		<-release
+		return c.String(http.StatusOK, "done")
+	})
+
+	assert.Equal(t, 0, te.ActiveRequests())
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(te.AbsURL("/slow"))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		<-arrived
+	}
+	assert.Equal(t, n, te.ActiveRequests())
+
+	close(release)
+	wg.Wait()
+
+	// The count is decremented after the response is sent, so allow a moment.
+	deadline := time.Now().Add(time.Second)
+	for te.ActiveRequests() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 5)
+	}
+	assert.Equal(t, 0, te.ActiveRequests())
+}
+
+func TestOnStop(t *testing.T) {
+
+	te := New()
+
+	var calls []string
+	te.OnStop(func() { calls = append(calls, "first") })
+	te.OnStop(func() { calls = append(calls, "second") })
+	assert.Empty(t, calls)
+
+	te.Stop()
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	// Callbacks only run once
+	te.Stop()
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
+func TestNewWithWriteTimeout(t *testing.T) {
+
+	te, err := NewWith(&Config{WriteTimeout: time.Millisecond * 100})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.GET("/fast", func(c echo.Context) error {
+		return c.String(http.StatusOK, "fast")
+	})
+	te.GET("/slow", func(c echo.Context) error {
+		time.Sleep(time.Millisecond * 300)
+		return c.String(http.StatusOK, "slow")
+	})
+
+	status, _, err := te.Get("/fast")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// The write deadline has passed by the time the slow handler responds,
+	// so the connection is closed without a response.
+	_, _, err = te.Get("/slow")
+	require.NotNil(t, err)
+}
+
+func TestNewWithIdleTimeout(t *testing.T) {
+
+	idle := time.Millisecond * 100
+	te, err := NewWith(&Config{IdleTimeout: idle})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	conn, err := net.Dial("tcp", te.Addr.String())
+	require.Nil(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, te.AbsURL("/hello"), nil)
+		require.Nil(t, err)
+		err = req.Write(conn)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.ReadResponse(r, req)
+		if err != nil {
+			return nil, err
+		}
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, err
+	}
+
+	resp, err := get()
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The server reaps the connection once it has been idle for too long,
+	// so the next request on that connection fails.
+	time.Sleep(idle * 3)
+	_, err = get()
+	require.NotNil(t, err)
+
+	// A client that reconnects succeeds.
+	status, _, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2, te.ConnectionsAccepted())
+}
+
+func TestNewWithHealthPath(t *testing.T) {
+
+	te, err := NewWith(&Config{HealthPath: "/healthz"})
+	require.Nil(t, err)
+	defer te.Stop()
+
+	status, body, err := te.Get("/healthz")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", string(body))
+
+	// The health route survives Reset.
+	te.Reset()
+	status, body, err = te.Get("/healthz")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", string(body))
+}
+
+func TestNewWithHTTP2(t *testing.T) {
+
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().Protocol())
+	}
+
+	// HTTP/2 over TLS
+	te, err := NewWith(&Config{TLS: true, HTTP2: true})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.GET("/proto", handler)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(te.CertPEM())
+	client := &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	resp, err := client.Get(te.AbsURL("/proto"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	// Cleartext HTTP/2 (h2c)
+	te2, err := NewWith(&Config{HTTP2: true})
+	require.Nil(t, err)
+	defer te2.Stop()
+	te2.GET("/proto", handler)
+
+	client = &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+
+	resp2, err := client.Get(te2.AbsURL("/proto"))
+	require.Nil(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp2.Proto)
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestConfigLogger(t *testing.T) {
+
+	buf := &syncBuffer{}
+	te, err := NewWith(&Config{Logger: buf})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.GET("/log", func(c echo.Context) error {
+		c.Logger().Error("something went wrong")
+		return c.NoContent(http.StatusOK)
+	})
+
+	resp, err := http.Get(te.AbsURL("/log"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Contains(t, buf.String(), "something went wrong")
+
+	// The logger should survive a reset
+	te.Reset()
+	te.GET("/log", func(c echo.Context) error {
+		c.Logger().Error("something else went wrong")
+		return c.NoContent(http.StatusOK)
+	})
+
+	resp, err = http.Get(te.AbsURL("/log"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Contains(t, buf.String(), "something else went wrong")
+}
+
+func TestNewTLS(t *testing.T) {
+
+	te := NewTLS()
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	SkipDefaultClientInsecureTLSVerify()
+	resp, err := http.Get(te.AbsURL("/hello"))
+	defer resp.Body.Close()
+	require.Nil(t, err)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestNewDual(t *testing.T) {
+
+	httpTe, tlsTe, err := NewDual()
+	require.Nil(t, err)
+	defer httpTe.Stop()
+	defer tlsTe.Stop()
+
+	assert.False(t, httpTe.TLS)
+	assert.True(t, tlsTe.TLS)
+	assert.NotEqual(t, httpTe.Port, tlsTe.Port)
+	assert.True(t, strings.HasPrefix(httpTe.URL, "http://"))
+	assert.True(t, strings.HasPrefix(tlsTe.URL, "https://"))
+
+	httpTe.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	status, body, err := httpTe.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	status, body, err = tlsTe.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	// Middleware features apply to both servers, whichever they were invoked on.
+	tlsTe.SetResponseHeader("X-Feature", "on")
+	resp, err := http.Get(httpTe.AbsURL("/hello"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "on", resp.Header.Get("X-Feature"))
+
+	// Counters are per server.
+	assert.Equal(t, 2, httpTe.RequestCount(echo.GET, "/hello"))
+	assert.Equal(t, 1, tlsTe.RequestCount(echo.GET, "/hello"))
+
+	// Reset on either server resets both, which still share an echo instance.
+	tlsTe.Reset()
+	assert.True(t, httpTe.Echo == tlsTe.Echo)
+	for _, te := range []*Techo{httpTe, tlsTe} {
+		resp, err = te.Client().Get(te.AbsURL("/hello"))
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, te.URL)
+		assert.Empty(t, resp.Header.Get("X-Feature"), te.URL)
+	}
+
+	httpTe.Respond(echo.GET, "/again", http.StatusOK, "again")
+	httpTe.SetResponseHeader("X-Feature", "again")
+	for _, te := range []*Techo{httpTe, tlsTe} {
+		resp, err = te.Client().Get(te.AbsURL("/again"))
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, te.URL)
+		assert.Equal(t, []string{"again"}, resp.Header["X-Feature"], te.URL)
+	}
+}
+
+func TestCertFilePath(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	assert.Empty(t, te.CertFilePath())
+	assert.Empty(t, te.KeyFilePath())
+
+	te = NewTLS()
+	certPath, keyPath := te.CertFilePath(), te.KeyFilePath()
+	require.NotEmpty(t, certPath)
+	require.NotEmpty(t, keyPath)
+
+	b, err := ioutil.ReadFile(certPath)
+	require.Nil(t, err)
+	assert.Equal(t, te.CertPEM(), b)
+	_, err = os.Stat(keyPath)
+	require.Nil(t, err)
+
+	te.Stop()
+	assert.Empty(t, te.CertFilePath())
+	assert.Empty(t, te.KeyFilePath())
+	_, err = os.Stat(certPath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(keyPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewWithTempDir(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "techo-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	te, err := NewWith(&Config{TLS: true, TempDir: dir})
+	require.Nil(t, err)
+	assert.Equal(t, dir, filepath.Dir(te.CertFilePath()))
+	assert.Equal(t, dir, filepath.Dir(te.KeyFilePath()))
+
+	status, _, err := te.Get("/")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	te.Stop()
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Empty(t, files, "TLS files should be removed")
+
+	_, err = NewWith(&Config{TLS: true, TempDir: filepath.Join(dir, "nope")})
+	require.NotNil(t, err)
+}
+
+func TestTLSField(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	assert.False(t, te.TLS)
+
+	te2 := NewTLS()
+	defer te2.Stop()
+	assert.True(t, te2.TLS)
+
+	te3, err := NewWith(&Config{TLS: true})
+	require.Nil(t, err)
+	defer te3.Stop()
+	assert.True(t, te3.TLS)
+}
+
+func TestClient(t *testing.T) {
+
+	te := NewTLS()
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	// Note: no call to SkipDefaultClientInsecureTLSVerify
+	resp, err := te.Client().Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	te2 := New()
+	defer te2.Stop()
+	te2.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	resp2, err := te2.Client().Get(te2.AbsURL("/hello"))
+	require.Nil(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusOK, resp2.StatusCode)
+}
+
+func TestCertPEM(t *testing.T) {
+
+	te := NewTLS()
+	defer te.Stop()
+
+	block, _ := pem.Decode(te.CertPEM())
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Acme Co"}, cert.Subject.Organization)
+
+	block, _ = pem.Decode(te.KeyPEM())
+	require.NotNil(t, block)
+	assert.Equal(t, "RSA PRIVATE KEY", block.Type)
+
+	te2 := New()
+	defer te2.Stop()
+	assert.Nil(t, te2.CertPEM())
+	assert.Nil(t, te2.KeyPEM())
+}
+
+func TestNewTLSStopImmediately(t *testing.T) {
+
+	tlsFiles := func() []string {
+		certs, err := filepath.Glob(filepath.Join(os.TempDir(), "techo-tls-cert_*"))
+		require.Nil(t, err)
+		keys, err := filepath.Glob(filepath.Join(os.TempDir(), "techo-tls-key_*"))
+		require.Nil(t, err)
+		return append(certs, keys...)
+	}
+
+	before := len(tlsFiles())
+	for i := 0; i < 50; i++ {
+		te := NewTLS()
+		require.NotNil(t, te)
+		te.Stop()
+	}
+	assert.Equal(t, before, len(tlsFiles()))
+}
+
+func TestFinalizerRemovesTLSFiles(t *testing.T) {
+
+	// Start a TLS instance, and drop the reference without stopping it.
+	var u string
+	paths := func() []string {
+		te := NewTLS()
+		require.NotNil(t, te)
+		u = te.AbsURL("/")
+		return []string{te.certFilePath, te.keyFilePath}
+	}()
+	defer StopAll()
+
+	removed := func() bool {
+		for _, path := range paths {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				return false
+			}
+		}
+		return true
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for !removed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("TLS files not removed after GC: %v", paths)
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// The finalizer does not stop the server.
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get(u)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGCDoesNotStopServer(t *testing.T) {
+
+	// Start an instance, and keep only its URL.
+	var u string
+	stopped := make(chan struct{})
+	func() {
+		te := New()
+		require.NotNil(t, te)
+		te.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+		te.OnStop(func() { close(stopped) })
+		u = te.AbsURL("/hello")
+	}()
+	defer StopAll()
+
+	for i := 0; i < 5; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("server should not be stopped by GC")
+	default:
+	}
+
+	resp, err := http.Get(u)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTLSFileMode(t *testing.T) {
+
+	te := NewTLS()
+	defer te.Stop()
+
+	for _, path := range []string{te.certFilePath, te.keyFilePath} {
+		fi, err := os.Stat(path)
+		require.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), path)
+	}
+}
+
+func TestTLSWithUserCerts(t *testing.T) {
+
+	SetDefaultTLSCert(testCert, testKey)
+
+	te := NewTLS()
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	SkipDefaultClientInsecureTLSVerify()
+	resp, err := http.Get(te.AbsURL("/hello"))
+	defer resp.Body.Close()
+	require.Nil(t, err)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestNewWithUserCerts(t *testing.T) {
+
+	cfg := &Config{TLS: true, TLSCert: testCert, TLSKey: testKey}
+	te, err := NewWith(cfg)
+	require.Nil(t, err)
+	require.NotNil(t, te)
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	SkipDefaultClientInsecureTLSVerify()
+	resp, err := http.Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestNewWithCertFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "techo-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.Nil(t, ioutil.WriteFile(certFile, testCert, 0600))
+	require.Nil(t, ioutil.WriteFile(keyFile, testKey, 0600))
+
+	te, err := NewWith(&Config{TLS: true, TLSCertFile: certFile, TLSKeyFile: keyFile})
+	require.Nil(t, err)
+	require.NotNil(t, te)
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	assert.Equal(t, testCert, te.CertPEM())
+	status, body, err := te.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	// The user's files are not removed on stop.
+	te.Stop()
+	for _, path := range []string{certFile, keyFile} {
+		_, err = os.Stat(path)
+		assert.Nil(t, err, path)
+	}
+
+	_, err = NewWith(&Config{TLS: true, TLSCertFile: certFile})
+	require.NotNil(t, err)
+}
+
+func TestNewWithTLSConfig(t *testing.T) {
+
+	te, err := NewWith(&Config{TLS: true, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS13}})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+	assert.Empty(t, te.certFilePath, "no temp files are written")
+
+	get := func(maxVersion uint16) error {
+		client := te.Client()
+		client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = maxVersion
+		resp, err := client.Get(te.AbsURL("/hello"))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	require.NotNil(t, get(tls.VersionTLS12), "TLS 1.2 client should be rejected")
+	require.Nil(t, get(tls.VersionTLS13))
+
+	// A config with its own certificate.
+	pair, err := tls.X509KeyPair(testCert, testKey)
+	require.Nil(t, err)
+	te2, err := NewWith(&Config{TLS: true, TLSConfig: &tls.Config{Certificates: []tls.Certificate{pair}}})
+	require.Nil(t, err)
+	defer te2.Stop()
+	te2.Respond(echo.GET, "/hello", http.StatusOK, "hello world")
+
+	assert.Equal(t, strings.TrimSpace(string(testCert)), strings.TrimSpace(string(te2.CertPEM())))
+	status, body, err := te2.Get("/hello")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestClientCert(t *testing.T) {
+
+	clientCert, clientKey := newTestClientCert(t)
+
+	te, err := NewWith(&Config{TLS: true, ClientCAs: [][]byte{clientCert}, RequireClientCert: true})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.EnableRecording()
+	te.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello world")
+	})
+
+	// Without a client cert, the connection is rejected.
+	_, _, err = te.Get("/hello")
+	require.NotNil(t, err)
+
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	require.Nil(t, err)
+	client := te.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{pair}
+
+	resp, err := client.Get(te.AbsURL("/hello"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reqs := te.Requests()
+	require.Equal(t, 1, len(reqs))
+	require.NotNil(t, reqs[0].ClientCert)
+	assert.Equal(t, "techo-test-client", reqs[0].ClientCert.Subject.CommonName)
+
+	_, err = NewWith(&Config{ClientCAs: [][]byte{clientCert}})
+	require.NotNil(t, err, "client certs require TLS")
+}
+
+// newTestClientCert returns a PEM-encoded self-signed client cert and key. The
+// cert is its own CA.
+func newTestClientCert(t *testing.T) (cert []byte, key []byte) {
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "techo-test-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	require.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	require.Nil(t, err)
+
+	cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	key = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, key
+}
+
+func TestHostPort(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	host, port, err := net.SplitHostPort(te.HostPort())
+	require.Nil(t, err)
+	assert.Equal(t, te.Addr.IP.String(), host)
+	assert.Equal(t, strconv.Itoa(te.Port), port)
+
+	conn, err := net.Dial("tcp", te.HostPort())
+	require.Nil(t, err)
+	conn.Close()
+
+	te6, err := NewWith(&Config{IPv6: true})
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer te6.Stop()
+	assert.Equal(t, fmt.Sprintf("[::1]:%v", te6.Port), te6.HostPort())
+	host, _, err = net.SplitHostPort(te6.HostPort())
+	require.Nil(t, err)
+	assert.Equal(t, "::1", host)
+}
+
+func TestBaseURL(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	u := te.BaseURL()
+	require.NotNil(t, u)
+	assert.Equal(t, te.URL, u.String())
+
+	u.Path = "/search"
+	u.RawQuery = url.Values{"q": []string{"techo"}}.Encode()
+	assert.Equal(t, te.AbsURL("/search?q=techo"), u.String())
+
+	// Modifying u doesn't affect the next call
+	assert.Equal(t, te.URL, te.BaseURL().String())
+}
+
+func TestAbsURLf(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+
+	assert.Equal(t, te.URL, te.AbsURLf(""))
+	assert.Equal(t, te.URL+"/users/42", te.AbsURLf("/users/%d", 42))
+	assert.Equal(t, te.URL+"/users/42", te.AbsURLf("users/%d", 42))
+	assert.Equal(t, te.URL+"/search?q=techo&page=2", te.AbsURLf("/search?q=%s&page=%d", "techo", 2))
+}
+
+func TestSetHTTPErrorHandler(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.GET("/teapot", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot, "short and stout")
+	})
+
+	te.SetHTTPErrorHandler(func(err error, c echo.Context) {
+		he, ok := err.(*echo.HTTPError)
+		if !ok {
+			he = echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		c.JSON(he.Code, map[string]interface{}{"code": he.Code, "error": he.Message})
+	})
+
+	resp, err := http.Get(te.AbsURL("/teapot"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"code":418,"error":"short and stout"}`, string(body))
+
+	// The handler may be changed while requests are in flight.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(te.AbsURL("/teapot"))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		te.SetHTTPErrorHandler(func(err error, c echo.Context) {
+			c.String(http.StatusTeapot, "changed")
+		})
+	}
+	wg.Wait()
+
+	status, body, err := te.Get("/teapot")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusTeapot, status)
+	assert.Equal(t, "changed", string(body))
+
+	// A nil handler restores the default.
+	te.SetHTTPErrorHandler(nil)
+	status, body, err = te.Get("/teapot")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusTeapot, status)
+	assert.NotEqual(t, "changed", string(body))
+
+	te.SetHTTPErrorHandler(func(err error, c echo.Context) {
+		c.String(http.StatusTeapot, "changed")
+	})
+	te.Reset()
+	te.GET("/teapot", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot, "short and stout")
+	})
+	_, body, err = te.Get("/teapot")
+	require.Nil(t, err)
+	assert.NotEqual(t, "changed", string(body), "Reset restores the default handler")
+}
+
+func TestAbsURLWithQuery(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.GET("/search", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.QueryParam("q")+"|"+c.QueryParam("page"))
+	})
+
+	assert.Equal(t, te.AbsURL("/search"), te.AbsURLWithQuery("/search", nil))
+
+	query := url.Values{"q": []string{"fish & chips"}}
+	resp, err := http.Get(te.AbsURLWithQuery("/search?page=2", query))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "fish & chips|2", string(body))
+}
+
+func TestLogger(t *testing.T) {
+
+	assert.True(t, Logger == log.Default(), "Logger should default to the standard logger")
+
+	orig := Logger
+	defer func() { Logger = orig }()
+
+	buf := &syncBuffer{}
+	Logger = log.New(buf, "", 0)
+
+	te := New()
+	defer te.Stop()
+
+	// This will fail, and techo logs the error
+	te.Static("/nope", "/does/not/exist")
+	assert.Contains(t, buf.String(), "/does/not/exist")
+}
+
+func TestStatic(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "techo-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello world"), 0600)
+	require.Nil(t, err)
+
+	te := New()
+	defer te.Stop()
+	require.Nil(t, te.StaticE("/static", dir))
+
+	resp, err := http.Get(te.AbsURL("/static/hello.txt"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	err = te.StaticE("/nope", filepath.Join(dir, "does-not-exist"))
+	require.NotNil(t, err)
+}
+
+func TestStaticFS(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"hello.txt":       {Data: []byte("hello world")},
+		"data/thing.json": {Data: []byte(`{"name":"widget"}`)},
+	}
+
+	te := New()
+	defer te.Stop()
+	te.StaticFS("/fixtures/", http.FS(fsys))
+
+	status, body, err := te.Get("/fixtures/hello.txt")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", string(body))
+
+	status, body, err = te.Get("/fixtures/data/thing.json")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"name":"widget"}`, string(body))
+
+	status, _, err = te.Get("/fixtures/nope.txt")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestHandle(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.Handle(echo.GET, "/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "std")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "hello %v", r.URL.Query().Get("name"))
+	}))
+
+	resp, err := http.Get(te.AbsURL("/hello?name=world"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "std", resp.Header.Get("X-Handler"))
+	assert.Equal(t, "hello world", string(body))
+
+	status, _, err := te.Post("/hello", "text/plain", nil)
+	require.Nil(t, err)
+	assert.NotEqual(t, http.StatusAccepted, status, "only GET is registered")
+}
+
+func TestRoutes(t *testing.T) {
+
+	te, err := NewWith(&Config{HealthPath: "/healthz"})
+	require.Nil(t, err)
+	defer te.Stop()
+	te.Respond(echo.GET, "/users/:id", http.StatusOK, "user")
+	te.RespondTemplate(echo.POST, "/users", "created", http.StatusCreated)
+	te.Handle(echo.DELETE, "/users/:id", http.NotFoundHandler())
+
+	routes := te.Routes()
+	assert.Contains(t, routes, RouteInfo{Method: echo.GET, Path: "/healthz"})
+	assert.Contains(t, routes, RouteInfo{Method: echo.GET, Path: "/users/:id"})
+	assert.Contains(t, routes, RouteInfo{Method: echo.POST, Path: "/users"})
+	assert.Contains(t, routes, RouteInfo{Method: echo.DELETE, Path: "/users/:id"})
+	assert.Equal(t, 4, len(routes))
+	assert.Equal(t, "GET /users/:id", RouteInfo{Method: echo.GET, Path: "/users/:id"}.String())
+
+	te.Reset()
+	assert.Equal(t, []RouteInfo{{Method: echo.GET, Path: "/healthz"}}, te.Routes())
+}
+
 var testCert = []byte(`-----BEGIN CERTIFICATE-----
 MIICEzCCAXygAwIBAgIQMIMChMLGrR+QvmQvpwAU6zANBgkqhkiG9w0BAQsFADAS
 MRAwDgYDVQQKEwdBY21lIENvMCAXDTcwMDEwMTAwMDAwMFoYDzIwODQwMTI5MTYw
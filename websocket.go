@@ -0,0 +1,35 @@
+package techo
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo"
+)
+
+// WebSocketEcho registers a handler at path that upgrades the connection to a
+// WebSocket, and then echoes every message it receives back to the client,
+// until the client closes the connection.
+func (t *Techo) WebSocketEcho(path string) {
+
+	upgrader := websocket.Upgrader{}
+	t.GET(path, func(c echo.Context) error {
+
+		conn, err := upgrader.Upgrade(stdResponseWriter(c), stdRequest(c), nil)
+		if err != nil {
+			// The upgrader has already written an error response
+			return nil
+		}
+		defer conn.Close()
+
+		for {
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return nil
+			}
+
+			err = conn.WriteMessage(msgType, msg)
+			if err != nil {
+				return nil
+			}
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package techo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketEcho(t *testing.T) {
+
+	te := New()
+	defer te.Stop()
+	te.WebSocketEcho("/ws")
+
+	wsURL := "ws" + strings.TrimPrefix(te.AbsURL("/ws"), "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	for _, msg := range []string{"hello", "world"} {
+		err = conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		require.Nil(t, err)
+
+		msgType, got, err := conn.ReadMessage()
+		require.Nil(t, err)
+		assert.Equal(t, websocket.TextMessage, msgType)
+		assert.Equal(t, msg, string(got))
+	}
+}